@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter/lang"
+)
+
+// client надсилає скрипти HTTP API painter та отримує з нього знімки сцени.
+type client struct {
+	addr    string
+	token   string        // bearer токен; порожній, якщо сервер не вимагає автентифікації
+	retries int           // кількість повторних спроб після помилки мережі або сервера (див. retryable)
+	backoff time.Duration // затримка перед першою повторною спробою; далі вона подвоюється
+	http    http.Client
+}
+
+// errRejected помилка, з якою сервер відхилив запит; такі запити не повторюються.
+type errRejected struct {
+	status int
+	body   string
+}
+
+func (e *errRejected) Error() string {
+	if e.body == "" {
+		return http.StatusText(e.status)
+	}
+	return strings.TrimSpace(e.body)
+}
+
+// newClient створює клієнт для адреси виду host:port, URL сервера (http:// або https://) або unix:<шлях до сокета>.
+func newClient(addr, token string, retries int) *client {
+	c := &client{addr: addr, token: token, retries: retries, backoff: 200 * time.Millisecond}
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		c.addr = "painter"
+		c.http.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+	}
+	return c
+}
+
+func (c *client) url(path string) string {
+	if strings.Contains(c.addr, "://") {
+		return c.addr + path
+	}
+	return "http://" + c.addr + path
+}
+
+// send надсилає скрипт одним запитом.
+func (c *client) send(script string) error {
+	_, err := c.do(http.MethodPost, "/", []byte(script))
+	return err
+}
+
+// get повертає тіло відповіді на GET запит.
+func (c *client) get(path string) ([]byte, error) {
+	return c.do(http.MethodGet, path, nil)
+}
+
+func (c *client) do(method, path string, body []byte) ([]byte, error) {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		data, err := c.try(method, path, body)
+		if err == nil || !retryable(method, err) || attempt >= c.retries {
+			return data, err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// retryable повідомляє, чи можна повторити запит після помилки err. GET запити нічого не змінюють, тож повторюються
+// після будь-якої помилки мережі чи сервера. Скрипт після обірваного з'єднання чи помилки 5xx міг бути вже
+// виконаний, тож його повторюємо лише тоді, коли з'єднання не вдалося встановити і сервер його точно не отримав.
+func retryable(method string, err error) bool {
+	var rejected *errRejected
+	if errors.As(err, &rejected) {
+		return method == http.MethodGet && rejected.status >= 500
+	}
+	if method == http.MethodGet {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func (c *client) try(method, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, c.url(path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &errRejected{status: resp.StatusCode, body: string(data)}
+	}
+	return data, nil
+}
+
+// batches ділить скрипт на частини по size команд, не розриваючи оголошення груп; size 0 означає весь скрипт.
+func batches(script string, size int) []string {
+	var (
+		result []string
+		batch  strings.Builder
+		count  int
+		depth  int
+	)
+	for _, line := range strings.Split(script, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		batch.WriteString(line + "\n")
+		count++
+		depth += lang.GroupDepth(line)
+		if size > 0 && count >= size && depth <= 0 {
+			result = append(result, batch.String())
+			batch.Reset()
+			count, depth = 0, 0
+		}
+	}
+	if batch.Len() != 0 {
+		result = append(result, batch.String())
+	}
+	return result
+}
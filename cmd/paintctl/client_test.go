@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatches(t *testing.T) {
+	script := "white\n\ngroup g {\nfigure 0.5 0.5\nfigure 0.25 0.25\n}\nupdate\n"
+	assert.Equal(t, []string{"white\ngroup g {\nfigure 0.5 0.5\nfigure 0.25 0.25\n}\nupdate\n"}, batches(script, 0))
+	// A group declaration is never split between batches.
+	assert.Equal(t, []string{"white\ngroup g {\nfigure 0.5 0.5\nfigure 0.25 0.25\n}\n", "update\n"}, batches(script, 2))
+	assert.Equal(t, []string{"white\n", "group g {\nfigure 0.5 0.5\nfigure 0.25 0.25\n}\n", "update\n"}, batches(script, 1))
+	assert.Empty(t, batches("\n\n", 1))
+}
+
+func TestClient_Retries(t *testing.T) {
+	var scripts, gets int
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets++
+		} else {
+			body, _ := io.ReadAll(r.Body)
+			scripts++
+			if string(body) == "bad" {
+				http.Error(rw, "invalid command bad", http.StatusBadRequest)
+				return
+			}
+		}
+		http.Error(rw, "try later", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	c := newClient(server.URL, "", 2)
+	c.backoff = 0
+
+	// The server may have run the script before failing, so it is sent once.
+	assert.EqualError(t, c.send("white"), "try later")
+	assert.Equal(t, 1, scripts)
+	assert.EqualError(t, c.send("bad"), "invalid command bad")
+	assert.Equal(t, 2, scripts)
+
+	_, err := c.get("/scene")
+	assert.Error(t, err)
+	assert.Equal(t, 3, gets)
+}
+
+func TestRetryable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	// Nothing listens on addr, so the script never reached the server and can be sent again.
+	_, err = newClient(addr, "", 0).try(http.MethodPost, "/", []byte("white"))
+	require.Error(t, err)
+	assert.True(t, retryable(http.MethodPost, err))
+	assert.False(t, retryable(http.MethodPost, &errRejected{status: http.StatusBadGateway}))
+	assert.True(t, retryable(http.MethodGet, &errRejected{status: http.StatusBadGateway}))
+	assert.False(t, retryable(http.MethodGet, &errRejected{status: http.StatusNotFound}))
+}
+
+func TestComplete(t *testing.T) {
+	line, pos, ok := complete("fig", 3, '\t')
+	require.True(t, ok)
+	assert.Equal(t, "figure ", line)
+	assert.Equal(t, 7, pos)
+
+	// Several matches are completed to their common prefix.
+	line, pos, ok = complete("re", 2, '\t')
+	require.True(t, ok)
+	assert.Equal(t, 2, pos)
+	assert.Equal(t, "re", line)
+
+	_, _, ok = complete("figure 0", 8, '\t')
+	assert.False(t, ok, "arguments are not completed")
+	_, _, ok = complete("fig", 3, 'x')
+	assert.False(t, ok)
+}
@@ -0,0 +1,103 @@
+// Команда paintctl - клієнт HTTP API painter.
+//
+//	paintctl [-addr address] [-retries n] send [-batch n] [file ...]  надіслати файли або stdin
+//	paintctl [-addr address] snapshot [-format png|svg|json] [-o file] отримати знімок сцени
+//	paintctl [-addr address] repl                                     інтерактивний режим
+//
+// Без підкоманди paintctl запускає repl, якщо stdin - термінал, і send в іншому разі.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:17000", "painter server address: host:port, http(s) URL or unix:<socket>")
+	token := flag.String("token", os.Getenv("PAINTER_TOKEN"), "bearer token for the server (env PAINTER_TOKEN)")
+	retries := flag.Int("retries", 3, "number of retries after network or server errors; scripts are retried only if the connection failed")
+	flag.Parse()
+
+	c := newClient(*addr, *token, *retries)
+	args := flag.Args()
+	command := "send"
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		command = "repl"
+	}
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	var err error
+	switch command {
+	case "send":
+		err = sendCommand(c, args)
+	case "snapshot":
+		err = snapshotCommand(c, args)
+	case "repl":
+		err = repl(c)
+	default:
+		err = fmt.Errorf("unknown command %q", command)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "paintctl:", err)
+		os.Exit(1)
+	}
+}
+
+// sendCommand надсилає скрипти з файлів (або stdin) частинами по -batch команд.
+func sendCommand(c *client, args []string) error {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	batch := fs.Int("batch", 0, "number of commands per request (0 sends each file in one request)")
+	_ = fs.Parse(args)
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, name := range files {
+		var data []byte
+		var err error
+		if name == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(name)
+		}
+		if err != nil {
+			return err
+		}
+		for _, script := range batches(string(data), *batch) {
+			if err := c.send(script); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// snapshotCommand завантажує поточну сцену як PNG, SVG або JSON документ.
+func snapshotCommand(c *client, args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	format := fs.String("format", "png", "snapshot format: png, svg or json")
+	output := fs.String("o", "", "output file (stdout if empty)")
+	_ = fs.Parse(args)
+
+	paths := map[string]string{"png": "/snapshot.png", "svg": "/scene.svg", "json": "/scene"}
+	path, ok := paths[*format]
+	if !ok {
+		return fmt.Errorf("unknown snapshot format %q", *format)
+	}
+	data, err := c.get(path)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0644)
+}
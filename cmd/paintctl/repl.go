@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter/lang"
+	"golang.org/x/term"
+)
+
+const (
+	prompt             = "painter> "
+	continuationPrompt = "....... "
+)
+
+// repl читає команди інтерактивно та надсилає кожну з них на сервер. Рядки оголошення групи накопичуються до
+// закривної дужки й надсилаються разом. У терміналі доступні історія команд (стрілки) та автодоповнення (Tab).
+func repl(c *client) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return readCommands(c, os.Stdin, os.Stderr)
+	}
+
+	state, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(os.Stdin.Fd()), state)
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, prompt)
+	t.AutoCompleteCallback = complete
+
+	var (
+		script strings.Builder
+		depth  int
+	)
+	for {
+		line, err := t.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		script.WriteString(line + "\n")
+		if depth += lang.GroupDepth(line); depth > 0 {
+			t.SetPrompt(continuationPrompt)
+			continue
+		}
+		if err := c.send(script.String()); err != nil {
+			fmt.Fprintf(t, "error: %s\n", err)
+		}
+		script.Reset()
+		depth = 0
+		t.SetPrompt(prompt)
+	}
+}
+
+// readCommands надсилає команди, прочитані не з термінала, групуючи оголошення груп так само, як repl. Помилки
+// сервера виводяться, але не переривають читання.
+func readCommands(c *client, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	var (
+		script strings.Builder
+		depth  int
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		script.WriteString(line + "\n")
+		if depth += lang.GroupDepth(line); depth > 0 {
+			continue
+		}
+		if err := c.send(script.String()); err != nil {
+			fmt.Fprintf(out, "error: %s\n", err)
+		}
+		script.Reset()
+		depth = 0
+	}
+	return scanner.Err()
+}
+
+// complete доповнює назву команди за префіксом; якщо варіантів декілька, доповнюється їх спільний префікс,
+// а самі варіанти не виводяться, щоб не псувати рядок вводу.
+func complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' || strings.ContainsAny(line[:pos], " \t") {
+		return "", 0, false
+	}
+	prefix := line[:pos]
+	var matches []string
+	for _, command := range lang.Commands {
+		if strings.HasPrefix(command, prefix) {
+			matches = append(matches, command)
+		}
+	}
+	if len(matches) == 0 {
+		return "", 0, false
+	}
+	sort.Strings(matches)
+	common := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(matches) == 1 {
+		common += " "
+	}
+	return common + line[pos:], len(common), true
+}
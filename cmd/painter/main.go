@@ -1,14 +1,57 @@
 package main
 
 import (
+	"flag"
+	"log"
+	"net"
 	"net/http"
+	"os"
 
 	"github.com/NikitaSutulov/software-architecture-lab3/painter"
 	"github.com/NikitaSutulov/software-architecture-lab3/painter/lang"
 	"github.com/NikitaSutulov/software-architecture-lab3/ui"
+	"golang.org/x/exp/shiny/screen"
 )
 
+// commands підкоманди, що виконуються замість запуску вікна з HTTP сервером.
+var commands = map[string]func(args []string) error{
+	"svg":    svgCommand,
+	"replay": replayCommand,
+	"render": renderCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
+	journalPath := flag.String("journal", "", "file to append accepted scripts to and to replay on startup")
+	compactAfter := flag.Int("journal-compact", lang.DefaultCompactAfter, "number of journal entries after which the journal is compacted into a scene snapshot")
+	recordPath := flag.String("record", "", "file to record accepted changes with their arrival times to")
+	framesDir := flag.String("frames", "", "directory to write every presented frame to as a numbered PNG")
+	capture := flag.Bool("capture", false, "draw frames in memory so that they can be recorded with record start and record stop; implied by -frames")
+	addr := flag.String("addr", envOr("PAINTER_ADDR", "localhost:17000"), "TCP address to serve commands on (env PAINTER_ADDR)")
+	unixPath := flag.String("unix", os.Getenv("PAINTER_UNIX"), "Unix socket to serve commands on instead of TCP (env PAINTER_UNIX)")
+	tcpAddr := flag.String("tcp", os.Getenv("PAINTER_TCP"), "TCP address to serve the line-based command protocol on; empty disables it (env PAINTER_TCP)")
+	certFile := flag.String("tls-cert", os.Getenv("PAINTER_TLS_CERT"), "TLS certificate file (env PAINTER_TLS_CERT)")
+	keyFile := flag.String("tls-key", os.Getenv("PAINTER_TLS_KEY"), "TLS key file (env PAINTER_TLS_KEY)")
+	tokensPath := flag.String("tokens", os.Getenv("PAINTER_TOKENS"), "file with bearer tokens and their permissions; empty disables authentication (env PAINTER_TOKENS)")
+	var limits lang.Limits
+	flag.Int64Var(&limits.MaxBodySize, "max-body", 1<<20, "maximum request body size in bytes (0 for no limit)")
+	flag.IntVar(&limits.MaxCommands, "max-commands", 0, "maximum number of commands in one script (0 for no limit)")
+	flag.Float64Var(&limits.Rate, "rate", 0, "requests per second allowed for one client (0 for no limit)")
+	flag.IntVar(&limits.Burst, "burst", 10, "number of requests a client may send at once")
+	flag.BoolVar(&limits.KeyByToken, "rate-by-token", false, "limit requests per bearer token instead of per client address")
+	readStdin := flag.Bool("stdin", false, "also execute commands read from stdin")
+	watchPath := flag.String("watch", "", "scene script to execute and re-execute whenever it changes")
+	sceneDir := flag.String("dir", envOr("PAINTER_DIR", "."), "directory for the scene files of save and load commands; empty disables them (env PAINTER_DIR)")
+	flag.Parse()
+
 	var (
 		pv ui.Visualizer // Візуалізатор створює вікно та малює у ньому.
 
@@ -20,12 +63,133 @@ func main() {
 	//pv.Debug = true
 	pv.Title = "Simple painter"
 
-	pv.OnScreenReady = opLoop.Start
-	opLoop.Receiver = &pv
+	// Кадри формуються у пам'яті лише тоді, коли потрібні їх пікселі: для запису анімації чи файлів кадрів. Інакше
+	// цикл подій малює одразу на текстурах вікна.
+	var recorder *painter.GIFRecorder
+	frames := &painter.FrameNotifier{Receiver: &pv}
+	pixels := *capture || *framesDir != ""
+	if pixels {
+		recorder = &painter.GIFRecorder{Receiver: &pv}
+		frames.Receiver = recorder
+		parser.Recorder = recorder
+	}
+	opLoop.Receiver = frames
+	events := &lang.Events{}
+	parser.Events = events
+	parser.Changes = &lang.ChangeLog{}
+	parser.Dir = *sceneDir
+	parser.Frames = frames
+	defer events.PublishFrames(frames)()
+	if *framesDir != "" {
+		dumper := &painter.FrameDumper{Receiver: frames, Dir: *framesDir}
+		defer dumper.Close()
+		opLoop.Receiver = dumper
+	}
+
+	// Дії, що виконуються, щойно цикл подій готовий приймати операції.
+	var startup []func()
+
+	if *journalPath != "" {
+		journal, err := lang.OpenJournal(*journalPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer journal.Close()
+		journal.CompactAfter = *compactAfter
+		parser.Journal = journal
+
+		// Журнал відтворюється до запуску серверів, щоб запити клієнтів не змішувалися з відтворенням. Операції
+		// чекають у черзі, доки цикл подій не запуститься.
+		if err := journal.Replay(&parser, &opLoop); err != nil {
+			log.Printf("Failed to replay journal: %s", err)
+		}
+	}
+	if *readStdin {
+		startup = append(startup, func() { go readScripts(os.Stdin, &opLoop, &parser) })
+	}
+	if *watchPath != "" {
+		startup = append(startup, func() { go watchScript(*watchPath, &opLoop, &parser) })
+	}
+	pv.OnScreenReady = func(s screen.Screen) {
+		if pixels {
+			s = painter.ImageScreen{}
+		}
+		opLoop.Start(s)
+		for _, start := range startup {
+			start()
+		}
+	}
+
+	if *recordPath != "" {
+		f, err := os.Create(*recordPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		parser.Session = lang.NewRecorder(f)
+	}
+
+	var auth *lang.Auth
+	if *tokensPath != "" {
+		var err error
+		if auth, err = lang.LoadTokens(*tokensPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	limits.Auth = auth
+
+	http.Handle("/", auth.Handler(limits.CommandLimit(lang.HttpHandler(&opLoop, &parser)), lang.ScriptPermission))
+	http.Handle("/scene", auth.Handler(lang.SceneHandler(&opLoop, &parser), lang.Require(lang.PermissionAdmin)))
+	http.Handle("/state", auth.Handler(lang.StateHandler(&parser), lang.Require(lang.PermissionRead)))
+	http.Handle("/hit", auth.Handler(lang.HitHandler(&parser), lang.Require(lang.PermissionRead)))
+	http.Handle("/bounds", auth.Handler(lang.BoundsHandler(&parser), lang.Require(lang.PermissionRead)))
+	http.Handle("/scene.svg", auth.Handler(lang.SVGHandler(&parser), lang.Require(lang.PermissionRead)))
+	http.Handle("/snapshot.png", auth.Handler(lang.SnapshotHandler(&parser), lang.Require(lang.PermissionRead)))
+	historyHandler := auth.Handler(lang.HistoryHandler(&opLoop, &parser), lang.Require(lang.PermissionDraw))
+	http.Handle("/history", historyHandler)
+	http.Handle("/undo", historyHandler)
+	http.Handle("/redo", historyHandler)
+	http.Handle("/ws", auth.Handler(lang.WebSocketHandler(&opLoop, &parser, frames, &limits), lang.Always(lang.PermissionDraw)))
+	http.Handle("/changes", auth.Handler(lang.ChangesHandler(parser.Changes), lang.Require(lang.PermissionRead)))
+	http.Handle("/events", auth.Handler(lang.EventsHandler(events), lang.Require(lang.PermissionRead)))
+	http.Handle("/viewer", lang.ViewerHandler())
+	http.Handle("/stream.mjpeg", auth.QueryHandler(lang.MJPEGHandler(frames, &parser), lang.Require(lang.PermissionRead)))
+	if recorder != nil {
+		http.Handle("/record/", auth.Handler(lang.RecordHandler(&opLoop, &parser), lang.Require(lang.PermissionAdmin)))
+	}
 
+	// Сокет відкривається, а сертифікат читається до створення вікна, щоб помилка одразу завершувала програму.
+	listener, err := listen(*addr, *unixPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tlsConfig, err := loadTLS(*certFile, *keyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *tcpAddr != "" {
+		lineListener, err := net.Listen("tcp", *tcpAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer lineListener.Close()
+		// Протокол не передає токенів, тож з увімкненими токенами адміністративні команди через нього недоступні.
+		granted := lang.PermissionAdmin
+		if auth != nil {
+			granted = lang.PermissionDraw
+		}
+		go func() {
+			if err := lang.ServeTCP(lineListener, &opLoop, &parser, granted, &limits); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+	server := http.Server{Handler: limits.Handler(http.DefaultServeMux), TLSConfig: tlsConfig}
+	defer server.Close()
 	go func() {
-		http.Handle("/", lang.HttpHandler(&opLoop, &parser))
-		_ = http.ListenAndServe("localhost:17000", nil)
+		if err := serve(&server, listener); err != nil {
+			log.Fatal(err)
+		}
 	}()
 
 	pv.Main()
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"github.com/NikitaSutulov/software-architecture-lab3/painter/lang"
+)
+
+// renderCommand виконує скрипт та записує отриману сцену у PNG файл. Сцена квадратна, тому розмір зображення задає
+// лише рівномірний масштаб, а сцена малюється одразу у цьому розмірі.
+func renderCommand(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	input := fs.String("i", "", "script file (stdin if empty)")
+	dir := fs.String("dir", ".", "directory for the scene files of save and load commands")
+	output := fs.String("o", "", "PNG file (stdout if empty)")
+	sizeFlag := fs.String("size", fmt.Sprintf("%[1]dx%[1]d", painter.CanvasSize), "image size as NxN")
+	_ = fs.Parse(args)
+
+	var size image.Point
+	if _, err := fmt.Sscanf(*sizeFlag, "%dx%d", &size.X, &size.Y); err != nil || size.X <= 0 || size.X != size.Y {
+		return fmt.Errorf("invalid size %q: expected NxN, the scene is square", *sizeFlag)
+	}
+
+	in, name, err := openInput(*input)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	parser := lang.Parser{Dir: *dir}
+	if _, err := parser.Parse(in); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	img, err := lang.RenderSize(parser.Scene(), size.X)
+	if err != nil {
+		return err
+	}
+
+	out, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(out, img); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// openInput відкриває файл скрипту або stdin, якщо шлях порожній, і повертає назву джерела для повідомлень про помилки.
+func openInput(path string) (io.ReadCloser, string, error) {
+	if path == "" {
+		return io.NopCloser(os.Stdin), "stdin", nil
+	}
+	f, err := os.Open(path)
+	return f, path, err
+}
+
+// createOutput створює файл результату або повертає stdout, якщо шлях порожній.
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
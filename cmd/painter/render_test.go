@@ -0,0 +1,45 @@
+package main
+
+import (
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderCommand(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "scene.txt")
+	require.NoError(t, os.WriteFile(script, []byte("green\nbgrect 0 0 0.125 0.125\n"), 0644))
+	out := filepath.Join(dir, "out.png")
+
+	require.NoError(t, renderCommand([]string{"-i", script, "-o", out, "-size", "400x400"}))
+	f, err := os.Open(out)
+	require.NoError(t, err)
+	defer f.Close()
+	img, err := png.Decode(f)
+	require.NoError(t, err)
+	assert.Equal(t, 400, img.Bounds().Dx())
+	assert.Equal(t, 400, img.Bounds().Dy())
+	assert.Equal(t, color.RGBA{A: 0xff}, color.RGBAModel.Convert(img.At(25, 25)))
+	assert.Equal(t, color.RGBA{G: 0xff, A: 0xff}, color.RGBAModel.Convert(img.At(75, 75)))
+}
+
+func TestRenderCommand_Errors(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "scene.txt")
+	require.NoError(t, os.WriteFile(script, []byte("green\nfigure 0.5\n"), 0644))
+	out := filepath.Join(dir, "out.png")
+
+	err := renderCommand([]string{"-i", script, "-o", out})
+	assert.EqualError(t, err, script+": line 2: wrong number of arguments for 'figure' command")
+	_, err = os.Stat(out)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Error(t, renderCommand([]string{"-i", script, "-o", out, "-size", "400x300"}))
+	assert.Error(t, renderCommand([]string{"-i", filepath.Join(dir, "missing.txt"), "-o", out}))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"github.com/NikitaSutulov/software-architecture-lab3/painter/lang"
+	"github.com/NikitaSutulov/software-architecture-lab3/ui"
+	"golang.org/x/exp/shiny/screen"
+)
+
+// replayCommand відкриває вікно та відтворює у ньому сесію, записану з прапорцем -record.
+func replayCommand(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	speed := fs.Float64("speed", 1, "playback speed multiplier")
+	step := fs.Bool("step", false, "wait for Enter before each request")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: painter replay [-speed factor] [-step] <file>")
+	}
+	if *speed <= 0 {
+		return fmt.Errorf("speed must be positive")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		pv     ui.Visualizer
+		opLoop painter.Loop
+		parser lang.Parser
+	)
+	pv.Title = "Simple painter (replay)"
+	opLoop.Receiver = &pv
+
+	session := lang.Session{Speed: *speed}
+	if *step {
+		stdin := bufio.NewReader(os.Stdin)
+		session.Step = func(r lang.RecordedRequest) {
+			if r.Scene != nil {
+				fmt.Printf("%v: scene ", r.Offset)
+			} else {
+				fmt.Printf("%v: %q ", r.Offset, r.Script)
+			}
+			_, _ = stdin.ReadString('\n')
+		}
+	}
+
+	pv.OnScreenReady = func(s screen.Screen) {
+		opLoop.Start(s)
+		go func() {
+			if err := session.Replay(f, &parser, &opLoop); err != nil {
+				log.Printf("Failed to replay session: %s", err)
+			}
+		}()
+	}
+
+	pv.Main()
+	opLoop.StopAndWait()
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"github.com/NikitaSutulov/software-architecture-lab3/painter/lang"
+)
+
+// watchInterval період, з яким перевіряється зміна файлу сцени.
+const watchInterval = 500 * time.Millisecond
+
+// readScripts виконує команди, прочитані з in, по одній: кожна команда (або оголошення групи цілком) розбирається
+// як окремий скрипт, а отримані операції передаються у цикл подій. Помилки лише логуються.
+func readScripts(in io.Reader, loop *painter.Loop, p *lang.Parser) {
+	scanner := bufio.NewScanner(in)
+	var (
+		script strings.Builder
+		depth  int
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		script.WriteString(line + "\n")
+		if depth += lang.GroupDepth(line); depth > 0 {
+			continue
+		}
+
+		ops, err := p.Parse(strings.NewReader(script.String()))
+		if err != nil {
+			log.Printf("Bad script: %s", err)
+		} else {
+			loop.Post(painter.OperationList(ops))
+		}
+		script.Reset()
+		depth = 0
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Failed to read scripts: %s", err)
+	}
+}
+
+// watchScript виконує файл сцени і повторює це щоразу, коли файл змінюється. Файл описує сцену повністю: він
+// розбирається окремим парсером, і отримана сцена замінює поточну, тож скрипт з помилкою не змінює полотно.
+func watchScript(path string, loop *painter.Loop, p *lang.Parser) {
+	var last os.FileInfo
+	for ; ; time.Sleep(watchInterval) {
+		info, err := os.Stat(path)
+		if err != nil {
+			if last != nil || !os.IsNotExist(err) {
+				log.Printf("Failed to watch scene file: %s", err)
+			}
+			last = nil
+			continue
+		}
+		if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+
+		if err := loadScript(path, loop, p); err != nil {
+			log.Printf("Bad scene file: %s", err)
+		}
+	}
+}
+
+func loadScript(path string, loop *painter.Loop, p *lang.Parser) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scene := lang.Parser{Dir: p.Dir}
+	if _, err := scene.Parse(f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	ops, err := p.SetScene(scene.Scene())
+	if err != nil {
+		return err
+	}
+	loop.Post(painter.OperationList(ops))
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"github.com/NikitaSutulov/software-architecture-lab3/painter/lang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadScripts(t *testing.T) {
+	var loop painter.Loop
+	parser := &lang.Parser{}
+
+	// Each command is executed on its own, a group declaration as a whole, and a bad command is skipped.
+	readScripts(strings.NewReader("figure 0.5 0.5\n\ngroup g {\nfigure 0.25 0.25\n}\nbad\nfigure 0.75 0.75\n"), &loop, parser)
+	assert.Len(t, loop.Mq.Ops, 3)
+	scene := parser.Scene()
+	assert.Len(t, scene.Figures, 2)
+	require.Len(t, scene.Groups, 1)
+	assert.Equal(t, "g", scene.Groups[0].Name)
+}
+
+func TestLoadScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scene.txt")
+	var loop painter.Loop
+	parser := &lang.Parser{}
+	_, err := parser.Parse(strings.NewReader("figure 0.1 0.1\nfigure 0.2 0.2"))
+	require.NoError(t, err)
+
+	// The file replaces the scene.
+	require.NoError(t, os.WriteFile(path, []byte("green\nfigure 0.5 0.5\n"), 0644))
+	require.NoError(t, loadScript(path, &loop, parser))
+	assert.Len(t, loop.Mq.Ops, 1)
+	assert.Len(t, parser.Scene().Figures, 1)
+
+	// A file with an error leaves the scene as it was.
+	require.NoError(t, os.WriteFile(path, []byte("white\nbad\n"), 0644))
+	assert.EqualError(t, loadScript(path, &loop, parser), path+": line 2: invalid command bad")
+	assert.Len(t, loop.Mq.Ops, 1)
+	assert.NotNil(t, parser.Scene().Background)
+}
+
+func TestWatchScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scene.txt")
+	var loop painter.Loop
+	parser := &lang.Parser{}
+	go watchScript(path, &loop, parser)
+
+	// The file is executed once it appears and again after it changes.
+	require.NoError(t, os.WriteFile(path, []byte("figure 0.5 0.5\n"), 0644))
+	require.Eventually(t, func() bool { return len(parser.Scene().Figures) == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, os.WriteFile(path, []byte("figure 0.25 0.25\nfigure 0.75 0.75\n"), 0644))
+	require.Eventually(t, func() bool { return len(parser.Scene().Figures) == 2 }, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+)
+
+// envOr повертає значення змінної оточення name або def, якщо її не задано.
+func envOr(name, def string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	return def
+}
+
+// listen відкриває сокет сервера команд: Unix сокет, якщо задано unixPath, інакше TCP адресу addr. Застарілий файл
+// Unix сокета, що лишився після попереднього запуску, видаляється.
+func listen(addr, unixPath string) (net.Listener, error) {
+	if unixPath == "" {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot listen on %s: %w", addr, err)
+		}
+		return l, nil
+	}
+	if info, err := os.Stat(unixPath); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", unixPath); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("cannot listen on %s: socket is in use", unixPath)
+		}
+		if err := os.Remove(unixPath); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("unix", unixPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on %s: %w", unixPath, err)
+	}
+	return l, nil
+}
+
+// loadTLS читає сертифікат і ключ сервера. Якщо жоден файл не задано, повертає nil: сервер працює без TLS.
+func loadTLS(certFile, keyFile string) (*tls.Config, error) {
+	switch {
+	case certFile == "" && keyFile == "":
+		return nil, nil
+	case certFile == "" || keyFile == "":
+		return nil, errors.New("both TLS certificate and key must be set")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load TLS certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// serve обслуговує HTTP запити з сокета l, використовуючи TLS, якщо srv.TLSConfig задано. Закриття srv не
+// вважається помилкою.
+func serve(srv *http.Server, l net.Listener) error {
+	var err error
+	if srv.TLSConfig != nil {
+		err = srv.ServeTLS(l, "", "")
+	} else {
+		err = srv.Serve(l)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListen_Unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "painter.sock")
+
+	// A socket left by a previous run is replaced.
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	l, err := listen("", path)
+	require.NoError(t, err)
+	defer l.Close()
+	assert.Equal(t, "unix", l.Addr().Network())
+
+	// A socket in use is not taken over.
+	_, err = listen("", path)
+	assert.EqualError(t, err, "cannot listen on "+path+": socket is in use")
+}
+
+func TestLoadTLS(t *testing.T) {
+	config, err := loadTLS("", "")
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir)
+	_, err = loadTLS(certFile, "")
+	assert.Error(t, err)
+	_, err = loadTLS(certFile, filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+
+	config, err = loadTLS(certFile, keyFile)
+	require.NoError(t, err)
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	srv := &http.Server{
+		Handler:   http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+		TLSConfig: config,
+	}
+	done := make(chan error, 1)
+	go func() { done <- serve(srv, l) }()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Get("https://" + l.Addr().String())
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.NotNil(t, resp.TLS)
+	require.NoError(t, srv.Close())
+	assert.NoError(t, <-done)
+}
+
+// writeCertificate writes a self-signed certificate for localhost and its key to dir.
+func writeCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
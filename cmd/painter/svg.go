@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter/lang"
+)
+
+// svgCommand виконує скрипт та записує отриману сцену у форматі SVG.
+func svgCommand(args []string) error {
+	fs := flag.NewFlagSet("svg", flag.ExitOnError)
+	input := fs.String("i", "", "script file (stdin if empty)")
+	dir := fs.String("dir", ".", "directory for the scene files of save and load commands")
+	output := fs.String("o", "", "SVG file (stdout if empty)")
+	_ = fs.Parse(args)
+
+	in, name, err := openInput(*input)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	parser := lang.Parser{Dir: *dir}
+	if _, err := parser.Parse(in); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	out, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	if err := lang.WriteSVG(out, parser.Scene()); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
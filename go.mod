@@ -2,7 +2,14 @@ module github.com/NikitaSutulov/software-architecture-lab3
 
 go 1.20
 
-require github.com/stretchr/testify v1.8.2
+require (
+	github.com/stretchr/testify v1.8.2
+	golang.org/x/exp/shiny v0.0.0-20230420155640-133eef4313cb
+	golang.org/x/image v0.7.0
+	golang.org/x/mobile v0.0.0-20201217150744-e6ae53a27f4f
+	golang.org/x/net v0.10.0
+	golang.org/x/term v0.8.0
+)
 
 require (
 	dmitri.shuralyov.com/gpu/mtl v0.0.0-20221208032759-85de2813cf6b // indirect
@@ -11,9 +18,6 @@ require (
 	github.com/jezek/xgb v1.0.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.5.0 // indirect
-	golang.org/x/exp/shiny v0.0.0-20230420155640-133eef4313cb // indirect
-	golang.org/x/image v0.7.0 // indirect
-	golang.org/x/mobile v0.0.0-20201217150744-e6ae53a27f4f // indirect
-	golang.org/x/sys v0.5.0 // indirect
+	golang.org/x/sys v0.8.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,73 @@
+package painter
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Region область текстури, якою можна обмежити малювання.
+type Region interface {
+	Bounds() image.Rectangle
+	Contains(p image.Point) bool
+}
+
+// ClipRect прямокутна область відсікання.
+type ClipRect image.Rectangle
+
+func (r ClipRect) Bounds() image.Rectangle { return image.Rectangle(r).Canon() }
+
+func (r ClipRect) Contains(p image.Point) bool { return p.In(r.Bounds()) }
+
+// ClipOperation виконує операцію Operation, обмежуючи все її малювання областю Region.
+type ClipOperation struct {
+	Region    Region
+	Operation Operation
+}
+
+func (op *ClipOperation) Do(t screen.Texture) bool {
+	return op.Operation.Do(clip(t, op.Region))
+}
+
+// clip повертає текстуру, малювання на якій обмежене областю r; nil означає відсутність обмежень.
+func clip(t screen.Texture, r Region) screen.Texture {
+	if r == nil {
+		return t
+	}
+	return &clippedTexture{Texture: t, region: r}
+}
+
+// clippedTexture передає у текстуру лише ті частини Fill, що лежать усередині області відсікання.
+type clippedTexture struct {
+	screen.Texture
+	region Region
+}
+
+func (t *clippedTexture) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
+	dr = dr.Canon().Intersect(t.region.Bounds())
+	if dr.Empty() {
+		return
+	}
+	if _, ok := t.region.(ClipRect); ok {
+		t.Texture.Fill(dr, src, op)
+		return
+	}
+	paintRect(t.Texture, dr, regionPaint{region: t.region, c: src}, op)
+}
+
+// regionPaint однотонна заливка, що повертає nil поза областю region.
+type regionPaint struct {
+	region Region
+	c      color.Color
+}
+
+func (p regionPaint) At(x, y int) color.Color {
+	if p.region.Contains(image.Pt(x, y)) {
+		return p.c
+	}
+	return nil
+}
+
+func (p regionPaint) Translate(dx, dy int) Paint { return p }
@@ -0,0 +1,39 @@
+package painter
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/exp/shiny/screen"
+)
+
+func TestClipOperation_Rect(t *testing.T) {
+	textureMock := new(MockTexture)
+	textureMock.On("Bounds").Return(image.Rect(0, 0, 800, 800))
+	textureMock.On("Fill", mock.Anything, mock.Anything, screen.Src).Return()
+
+	op := &ClipOperation{Region: ClipRect(image.Rect(100, 100, 200, 200)), Operation: OperationFunc(WhiteFill)}
+	op.Do(textureMock)
+
+	textureMock.AssertCalled(t, "Fill", image.Rect(100, 100, 200, 200), color.White, screen.Src)
+	textureMock.AssertNumberOfCalls(t, "Fill", 1)
+}
+
+func TestClipOperation_Shape(t *testing.T) {
+	textureMock := new(MockTexture)
+	textureMock.On("Bounds").Return(image.Rect(0, 0, 800, 800))
+	textureMock.On("Fill", mock.Anything, mock.Anything, screen.Src).Return()
+
+	cross := &CrossFigure{CentralPoint: image.Pt(400, 400), ArmLength: 40, ArmWidth: 20}
+	op := &ClipOperation{Region: cross, Operation: OperationFunc(WhiteFill)}
+	op.Do(textureMock)
+
+	// The cross is split into three rectangles: the top part of the vertical arm, the horizontal arm
+	// and the bottom part of the vertical arm.
+	textureMock.AssertCalled(t, "Fill", image.Rect(390, 380, 410, 390), color.White, screen.Src)
+	textureMock.AssertCalled(t, "Fill", image.Rect(380, 390, 420, 410), color.White, screen.Src)
+	textureMock.AssertCalled(t, "Fill", image.Rect(390, 410, 410, 420), color.White, screen.Src)
+	textureMock.AssertNumberOfCalls(t, "Fill", 3)
+}
@@ -0,0 +1,157 @@
+package painter
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// FrameManifestName назва файлу в каталозі кадрів, у який FrameDumper записує опис кожного кадру.
+const FrameManifestName = "manifest.jsonl"
+
+// FrameInfo рядок маніфесту: файл кадру та час його показу.
+type FrameInfo struct {
+	Frame  int           `json:"frame"`
+	File   string        `json:"file"`
+	Time   time.Time     `json:"time"`
+	Offset time.Duration `json:"offset"` // час від показу першого кадру
+}
+
+// frameQueue кількість кадрів, які FrameDumper може тримати у черзі на запис. Якщо запис відстає більше, нові кадри
+// відкидаються, щоб не гальмувати цикл подій; їх кількість повертає Dropped.
+const frameQueue = 8
+
+// FrameDumper передає текстури іншому Receiver і записує кожен показаний кадр у каталог Dir як пронумерований PNG
+// файл, додаючи його опис у маніфест. Записуються лише кадри, сформовані у пам'яті (ImageTexture). Кадри кодуються
+// та записуються в окремій горутині, тож цикл подій лише копіює пікселі.
+type FrameDumper struct {
+	Receiver Receiver
+	Dir      string
+	// Now повертає поточний час; nil означає time.Now.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	queue   chan dumpedFrame // кадри, що чекають на запис; створюється з першим кадром
+	done    chan struct{}    // закривається, коли горутина запису завершилась
+	closed  bool
+	dropped int // кадри, відкинуті через переповнену чергу
+
+	// Поля нижче використовує лише горутина запису.
+	manifest *os.File
+	frames   int
+	start    time.Time
+}
+
+// dumpedFrame копія показаного кадру, що чекає на запис.
+type dumpedFrame struct {
+	img  *image.RGBA
+	time time.Time
+}
+
+func (d *FrameDumper) Update(t screen.Texture) {
+	if it, ok := t.(*ImageTexture); ok {
+		now := time.Now()
+		if d.Now != nil {
+			now = d.Now()
+		}
+		d.enqueue(dumpedFrame{img: it.Snapshot(), time: now})
+	}
+	if d.Receiver != nil {
+		d.Receiver.Update(t)
+	}
+}
+
+func (d *FrameDumper) enqueue(f dumpedFrame) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	if d.queue == nil {
+		d.queue, d.done = make(chan dumpedFrame, frameQueue), make(chan struct{})
+		go d.write(d.queue, d.done)
+	}
+	select {
+	case d.queue <- f:
+	default:
+		if d.dropped == 0 {
+			log.Printf("Dropping frames: writing frames to %s is too slow", d.Dir)
+		}
+		d.dropped++
+	}
+}
+
+// Dropped повертає кількість показаних кадрів, які не було записано, бо запис не встигав за показом.
+func (d *FrameDumper) Dropped() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.dropped
+}
+
+func (d *FrameDumper) write(queue <-chan dumpedFrame, done chan<- struct{}) {
+	defer close(done)
+	for f := range queue {
+		if err := d.dump(f); err != nil {
+			log.Printf("Failed to dump frame: %s", err)
+		}
+	}
+}
+
+func (d *FrameDumper) dump(frame dumpedFrame) error {
+	if d.manifest == nil {
+		if err := os.MkdirAll(d.Dir, 0755); err != nil {
+			return err
+		}
+		manifest, err := os.Create(filepath.Join(d.Dir, FrameManifestName))
+		if err != nil {
+			return err
+		}
+		d.manifest, d.start = manifest, frame.time
+	}
+
+	d.frames++
+	info := FrameInfo{Frame: d.frames, File: fmt.Sprintf("frame-%06d.png", d.frames), Time: frame.time, Offset: frame.time.Sub(d.start)}
+	f, err := os.Create(filepath.Join(d.Dir, info.File))
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, frame.img); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return json.NewEncoder(d.manifest).Encode(info)
+}
+
+// Close дочікується запису кадрів, що залишились у черзі, та закриває маніфест. Якщо частину кадрів було відкинуто,
+// їх кількість пишеться в лог. Кадри, показані після Close, не записуються.
+func (d *FrameDumper) Close() error {
+	d.mu.Lock()
+	queue, done := d.queue, d.done
+	closed, dropped := d.closed, d.dropped
+	d.closed = true
+	d.mu.Unlock()
+	if closed || queue == nil {
+		return nil
+	}
+
+	close(queue)
+	<-done
+	if dropped > 0 {
+		log.Printf("Dropped %d frames, %d were written to %s", dropped, d.frames, d.Dir)
+	}
+	if d.manifest == nil {
+		return nil
+	}
+	return d.manifest.Close()
+}
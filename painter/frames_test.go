@@ -0,0 +1,69 @@
+package painter
+
+import (
+	"bufio"
+	"encoding/json"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameDumper(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "frames")
+	now := time.Unix(100, 0)
+	dumper := &FrameDumper{Dir: dir, Now: func() time.Time { return now }}
+
+	texture := NewImageTexture(size)
+	WhiteFill(texture)
+	dumper.Update(texture)
+	now = now.Add(250 * time.Millisecond)
+	GreenFill(texture)
+	dumper.Update(texture)
+	dumper.Update(new(MockTexture)) // Textures outside memory are not dumped.
+	require.NoError(t, dumper.Close())
+
+	f, err := os.Open(filepath.Join(dir, FrameManifestName))
+	require.NoError(t, err)
+	defer f.Close()
+	var frames []FrameInfo
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var info FrameInfo
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &info))
+		frames = append(frames, info)
+	}
+	require.Len(t, frames, 2)
+	assert.Equal(t, "frame-000002.png", frames[1].File)
+	assert.Equal(t, 250*time.Millisecond, frames[1].Offset)
+
+	img, err := os.Open(filepath.Join(dir, frames[1].File))
+	require.NoError(t, err)
+	defer img.Close()
+	decoded, err := png.Decode(img)
+	require.NoError(t, err)
+	assertRGBA(t, color.RGBA{G: 0xff, A: 0xff}, decoded.At(10, 10))
+}
+
+func TestFrameDumper_Dropped(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "frames")
+	dumper := &FrameDumper{Dir: dir}
+	// The writer starts only after the queue is full, as if writing were too slow.
+	dumper.queue, dumper.done = make(chan dumpedFrame, frameQueue), make(chan struct{})
+
+	texture := NewImageTexture(size)
+	for i := 0; i < frameQueue+3; i++ {
+		dumper.Update(texture)
+	}
+	assert.Equal(t, 3, dumper.Dropped())
+
+	go dumper.write(dumper.queue, dumper.done)
+	require.NoError(t, dumper.Close())
+	files, err := filepath.Glob(filepath.Join(dir, "frame-*.png"))
+	require.NoError(t, err)
+	assert.Len(t, files, frameQueue)
+}
@@ -0,0 +1,104 @@
+package painter
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// point точка з дробовими координатами, використовується для обчислення повернутих фігур.
+type point struct {
+	X, Y float64
+}
+
+// rotatedRect повертає вершини прямокутника розміром w x h з центром у c, повернутого на angle градусів.
+func rotatedRect(c point, w, h, angle float64) []point {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	corners := []point{{-w / 2, -h / 2}, {w / 2, -h / 2}, {w / 2, h / 2}, {-w / 2, h / 2}}
+	for i, p := range corners {
+		corners[i] = point{
+			X: c.X + p.X*cos - p.Y*sin,
+			Y: c.Y + p.X*sin + p.Y*cos,
+		}
+	}
+	return corners
+}
+
+// fillRotatedRect зафарбовує прямокутник розміром w x h з центром у c, повернутий на angle градусів,
+// кольором col або заливкою p. Без повороту прямокутник однотонного кольору малюється одним викликом Fill.
+func fillRotatedRect(t screen.Texture, c image.Point, w, h, angle float64, col color.Color, p Paint) {
+	if math.Mod(angle, 360) == 0 {
+		fillArea(t, axisRect(c, w, h), col, p)
+		return
+	}
+	fillPolygon(t, rotatedRect(point{float64(c.X), float64(c.Y)}, w, h, angle), col, p)
+}
+
+// rotatedRectBounds повертає найменший прямокутник текстури, що містить повернутий прямокутник.
+func rotatedRectBounds(c image.Point, w, h, angle float64) image.Rectangle {
+	if math.Mod(angle, 360) == 0 {
+		return axisRect(c, w, h)
+	}
+	return polygonBounds(rotatedRect(point{float64(c.X), float64(c.Y)}, w, h, angle))
+}
+
+// rotatedRectContains перевіряє, чи зафарбовує fillRotatedRect піксель p.
+func rotatedRectContains(c image.Point, w, h, angle float64, p image.Point) bool {
+	if math.Mod(angle, 360) == 0 {
+		return p.In(axisRect(c, w, h))
+	}
+	return polygonContains(rotatedRect(point{float64(c.X), float64(c.Y)}, w, h, angle), p)
+}
+
+// axisRect повертає неповернутий прямокутник розміром w x h з центром у c.
+func axisRect(c image.Point, w, h float64) image.Rectangle {
+	hw, hh := int(math.Round(w/2)), int(math.Round(h/2))
+	return image.Rect(c.X-hw, c.Y+hh, c.X+hw, c.Y-hh)
+}
+
+// polygonBounds повертає найменший прямокутник текстури, що містить многокутник.
+func polygonBounds(pts []point) image.Rectangle {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range pts {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+}
+
+// polygonContains перевіряє, чи зафарбовує fillPolygon піксель p.
+func polygonContains(pts []point, p image.Point) bool {
+	x0, x1, ok := polygonSpan(pts, float64(p.Y)+0.5)
+	return ok && int(math.Round(x0)) <= p.X && p.X < int(math.Round(x1))
+}
+
+// fillPolygon зафарбовує опуклий многокутник кольором col або заливкою p, обробляючи кожен рядок пікселів окремо.
+func fillPolygon(t screen.Texture, pts []point, col color.Color, p Paint) {
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, p := range pts {
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	for y := int(math.Floor(minY)); y < int(math.Ceil(maxY)); y++ {
+		x0, x1, ok := polygonSpan(pts, float64(y)+0.5)
+		if !ok {
+			continue
+		}
+		fillArea(t, image.Rect(int(math.Round(x0)), y, int(math.Round(x1)), y+1), col, p)
+	}
+}
+
+// polygonSpan повертає межі перетину горизонталі y з опуклим многокутником.
+func polygonSpan(pts []point, y float64) (x0, x1 float64, ok bool) {
+	x0, x1 = math.Inf(1), math.Inf(-1)
+	for i := range pts {
+		a, b := pts[i], pts[(i+1)%len(pts)]
+		if (a.Y <= y && b.Y > y) || (b.Y <= y && a.Y > y) {
+			x := a.X + (y-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+			x0, x1 = math.Min(x0, x), math.Max(x1, x)
+		}
+	}
+	return x0, x1, x0 < x1
+}
@@ -0,0 +1,98 @@
+package painter
+
+import (
+	"image"
+	"image/color"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Shape операція малювання фігури, яку можна переміщувати та перефарбовувати.
+type Shape interface {
+	Operation
+	Region
+	Translate(dx, dy int)
+	SetColor(c color.Color)
+	SetPaint(p Paint)
+}
+
+// Group об'єднує фігури та вкладені групи, які малюються, переміщуються та перефарбовуються як одне ціле.
+type Group struct {
+	Name   string
+	Shapes []Shape
+	Hidden bool
+	Clip   Region // область, якою обмежене малювання групи; nil означає всю текстуру
+}
+
+func (g *Group) Do(t screen.Texture) bool {
+	if g.Hidden {
+		return false
+	}
+	t = clip(t, g.Clip)
+	for _, s := range g.Shapes {
+		s.Do(t)
+	}
+	return false
+}
+
+// Bounds повертає об'єднання меж усіх фігур групи.
+func (g *Group) Bounds() image.Rectangle {
+	var r image.Rectangle
+	for _, s := range g.Shapes {
+		r = r.Union(s.Bounds())
+	}
+	return r
+}
+
+func (g *Group) Contains(p image.Point) bool {
+	for _, s := range g.Shapes {
+		if s.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Group) Translate(dx, dy int) {
+	for _, s := range g.Shapes {
+		s.Translate(dx, dy)
+	}
+}
+
+func (g *Group) SetColor(c color.Color) {
+	for _, s := range g.Shapes {
+		s.SetColor(c)
+	}
+}
+
+func (g *Group) SetPaint(p Paint) {
+	for _, s := range g.Shapes {
+		s.SetPaint(p)
+	}
+}
+
+// Remove видаляє фігуру з групи або з будь-якої вкладеної групи, повертаючи true, якщо її знайдено.
+func (g *Group) Remove(shape Shape) bool {
+	for i, s := range g.Shapes {
+		if s == shape {
+			g.Shapes = append(g.Shapes[:i], g.Shapes[i+1:]...)
+			return true
+		}
+		if inner, ok := s.(*Group); ok && inner.Remove(shape) {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupMoveOperation зсуває всі фігури групи Group на X, Y.
+type GroupMoveOperation struct {
+	X     int
+	Y     int
+	Group *Group
+}
+
+func (op *GroupMoveOperation) Do(t screen.Texture) bool {
+	op.Group.Translate(op.X, op.Y)
+	return false
+}
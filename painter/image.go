@@ -0,0 +1,69 @@
+package painter
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// ImageTexture текстура, пікселі якої зберігаються у пам'яті, тож готовий кадр можна прочитати, наприклад для запису
+// анімації. Для відображення у вікні її вміст завантажується у текстуру вікна.
+type ImageTexture struct {
+	RGBA *image.RGBA
+}
+
+func NewImageTexture(size image.Point) *ImageTexture {
+	return &ImageTexture{RGBA: image.NewRGBA(image.Rectangle{Max: size})}
+}
+
+func (t *ImageTexture) Release() {}
+
+func (t *ImageTexture) Size() image.Point { return t.RGBA.Rect.Size() }
+
+func (t *ImageTexture) Bounds() image.Rectangle { return t.RGBA.Rect }
+
+func (t *ImageTexture) Upload(dp image.Point, src screen.Buffer, sr image.Rectangle) {
+	draw.Draw(t.RGBA, sr.Sub(sr.Min).Add(dp), src.RGBA(), sr.Min, draw.Src)
+}
+
+func (t *ImageTexture) Fill(dr image.Rectangle, src color.Color, op draw.Op) {
+	draw.Draw(t.RGBA, dr, image.NewUniform(src), image.Point{}, op)
+}
+
+// Snapshot повертає копію кадру. Як і у вікні, прозорість не враховується: усі пікселі копії непрозорі.
+func (t *ImageTexture) Snapshot() *image.RGBA {
+	img := image.NewRGBA(t.RGBA.Rect)
+	copy(img.Pix, t.RGBA.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+	return img
+}
+
+// ImageScreen screen.Screen, що створює текстури у пам'яті. Цикл подій, запущений з ImageScreen, формує кадри, які
+// можна не лише показати, а й записати.
+type ImageScreen struct{}
+
+func (ImageScreen) NewBuffer(size image.Point) (screen.Buffer, error) {
+	return imageBuffer{image.NewRGBA(image.Rectangle{Max: size})}, nil
+}
+
+func (ImageScreen) NewTexture(size image.Point) (screen.Texture, error) {
+	return NewImageTexture(size), nil
+}
+
+func (ImageScreen) NewWindow(opts *screen.NewWindowOptions) (screen.Window, error) {
+	return nil, errors.New("image screen cannot open windows")
+}
+
+type imageBuffer struct {
+	rgba *image.RGBA
+}
+
+func (b imageBuffer) Release()                {}
+func (b imageBuffer) Size() image.Point       { return b.rgba.Rect.Size() }
+func (b imageBuffer) Bounds() image.Rectangle { return b.rgba.Rect }
+func (b imageBuffer) RGBA() *image.RGBA       { return b.rgba }
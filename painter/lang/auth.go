@@ -0,0 +1,169 @@
+package lang
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Permission рівень доступу до HTTP API; кожен рівень включає попередні.
+type Permission int
+
+const (
+	PermissionRead  Permission = iota + 1 // отримання сцени, знімків та історії
+	PermissionDraw                        // виконання скриптів, undo та redo
+	PermissionAdmin                       // reset, заміна сцени, робота з файлами та запис анімації
+)
+
+var permissionNames = map[string]Permission{
+	"read":  PermissionRead,
+	"draw":  PermissionDraw,
+	"admin": PermissionAdmin,
+}
+
+// adminCommands команди, виконання яких у скрипті потребує PermissionAdmin.
+var adminCommands = map[string]bool{
+	"reset":  true,
+	"save":   true,
+	"load":   true,
+	"record": true,
+}
+
+// Auth перевіряє bearer токени запитів до HTTP API. Нульовий вказівник на Auth вимикає перевірку.
+type Auth struct {
+	tokens map[string]Permission
+}
+
+// LoadTokens читає файл токенів: кожен непорожній рядок, що не починається з '#', має вигляд "<token> read|draw|admin".
+func LoadTokens(path string) (*Auth, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	a := &Auth{tokens: make(map[string]Permission)}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		words := strings.Fields(scanner.Text())
+		if len(words) == 0 || strings.HasPrefix(words[0], "#") {
+			continue
+		}
+		permission, ok := permissionNames[words[len(words)-1]]
+		if len(words) != 2 || !ok {
+			return nil, fmt.Errorf("%s:%d: expected '<token> read|draw|admin'", path, line)
+		}
+		a.tokens[words[0]] = permission
+	}
+	return a, scanner.Err()
+}
+
+// Handler обгортає обробник перевіркою токена із заголовка Authorization: запит без відомого токена відхиляється
+// зі статусом 401, а запит, для якого permission вимагає вищого рівня доступу, ніж має токен, - зі статусом 403.
+func (a *Auth) Handler(h http.Handler, permission func(r *http.Request) (Permission, error)) http.Handler {
+	return a.handler(h, permission, false)
+}
+
+// QueryHandler обгортає обробник так само, як Handler, але приймає токен і з параметра access_token. Браузер не
+// може додати заголовок до запиту зображення, тож це потрібно лише для потоку кадрів; адреси з токеном потрапляють
+// у журнали та історію браузера, тому для інших обробників варто використовувати Handler.
+func (a *Auth) QueryHandler(h http.Handler, permission func(r *http.Request) (Permission, error)) http.Handler {
+	return a.handler(h, permission, true)
+}
+
+func (a *Auth) handler(h http.Handler, permission func(r *http.Request) (Permission, error), query bool) http.Handler {
+	if a == nil {
+		return h
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok && query {
+			token = r.URL.Query().Get("access_token")
+			ok = token != ""
+		}
+		granted := a.permission(strings.TrimSpace(token))
+		if !ok || granted == 0 {
+			rw.Header().Set("WWW-Authenticate", `Bearer realm="painter"`)
+			http.Error(rw, "missing or unknown token", http.StatusUnauthorized)
+			return
+		}
+		required, err := permission(r)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if granted < required {
+			http.Error(rw, "token is not allowed to perform this request", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), permissionKey{}, granted)))
+	})
+}
+
+// permission повертає рівень доступу токена або 0 для невідомого токена. Токени порівнюються за сталий час, щоб
+// час відповіді не підказував їх вміст.
+func (a *Auth) permission(token string) Permission {
+	var granted Permission
+	for known, p := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(token)) == 1 {
+			granted = p
+		}
+	}
+	return granted
+}
+
+// Require повертає функцію рівня доступу для обробника, якому потрібен рівень read для GET запитів та write для решти.
+func Require(write Permission) func(r *http.Request) (Permission, error) {
+	return func(r *http.Request) (Permission, error) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			return PermissionRead, nil
+		}
+		return write, nil
+	}
+}
+
+// ScriptPermission визначає рівень доступу, потрібний для виконання скрипту з запиту до HttpHandler: draw або admin,
+// якщо скрипт містить адміністративні команди. Тіло запиту зберігається для обробника.
+func ScriptPermission(r *http.Request) (Permission, error) {
+	script := r.URL.Query().Get("cmd")
+	if r.Method != http.MethodGet {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return 0, err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		script = string(body)
+	}
+	return scriptPermission(script), nil
+}
+
+func scriptPermission(script string) Permission {
+	for _, line := range strings.Split(script, "\n") {
+		if words := strings.Fields(line); len(words) != 0 && adminCommands[words[0]] {
+			return PermissionAdmin
+		}
+	}
+	return PermissionDraw
+}
+
+type permissionKey struct{}
+
+// Granted повертає рівень доступу токена, з яким Auth пропустив запит. Якщо токени не перевіряються, доступ
+// не обмежений. Потрібен обробникам, що виконують скрипти вже після встановлення з'єднання.
+func Granted(r *http.Request) Permission {
+	if p, ok := r.Context().Value(permissionKey{}).(Permission); ok {
+		return p
+	}
+	return PermissionAdmin
+}
+
+// Always повертає функцію рівня доступу для обробника, якому потрібен рівень p незалежно від запиту.
+func Always(p Permission) func(r *http.Request) (Permission, error) {
+	return func(*http.Request) (Permission, error) { return p, nil }
+}
@@ -0,0 +1,68 @@
+package lang
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth_Handler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(path, []byte("# viewers\nviewer read\nartist draw\n\nroot admin\n"), 0600))
+	auth, err := LoadTokens(path)
+	require.NoError(t, err)
+
+	var loop painter.Loop
+	parser := &Parser{}
+	scripts := auth.Handler(HttpHandler(&loop, parser), ScriptPermission)
+	scene := auth.Handler(SceneHandler(&loop, parser), Require(PermissionAdmin))
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		method  string
+		body    string
+		token   string
+		status  int
+	}{
+		{name: "no token", handler: scripts, method: http.MethodPost, body: "white", status: http.StatusUnauthorized},
+		{name: "unknown token", handler: scripts, method: http.MethodPost, body: "white", token: "guest", status: http.StatusUnauthorized},
+		{name: "read-only script", handler: scripts, method: http.MethodPost, body: "white", token: "viewer", status: http.StatusForbidden},
+		{name: "draw script", handler: scripts, method: http.MethodPost, body: "white\nupdate", token: "artist", status: http.StatusOK},
+		{name: "draw reset", handler: scripts, method: http.MethodPost, body: "white\nreset", token: "artist", status: http.StatusForbidden},
+		{name: "admin reset", handler: scripts, method: http.MethodPost, body: "white\nreset", token: "root", status: http.StatusOK},
+		{name: "read scene", handler: scene, method: http.MethodGet, token: "viewer", status: http.StatusOK},
+		{name: "draw replaces scene", handler: scene, method: http.MethodPut, body: "{}", token: "artist", status: http.StatusForbidden},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, "/", strings.NewReader(tc.body))
+			if tc.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rw := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rw, r)
+			assert.Equal(t, tc.status, rw.Code)
+		})
+	}
+
+	// Only the frame stream accepts the token in the query string.
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	rw := httptest.NewRecorder()
+	auth.Handler(ok, Require(PermissionRead)).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/?access_token=viewer", nil))
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+	rw = httptest.NewRecorder()
+	auth.QueryHandler(ok, Require(PermissionRead)).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/?access_token=viewer", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	require.NoError(t, os.WriteFile(path, []byte("root superuser\n"), 0600))
+	_, err = LoadTokens(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package lang
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultChangeLogSize кількість останніх змін, які зберігає ChangeLog.
+const DefaultChangeLogSize = 1000
+
+// DefaultChangesTimeout найдовший час, протягом якого ChangesHandler чекає на нові зміни.
+const DefaultChangesTimeout = 30 * time.Second
+
+// ErrConflict повідомляє, що скрипт написано для застарілої версії сцени.
+var ErrConflict = errors.New("version conflict")
+
+// ChangeLog журнал останніх прийнятих змін сцени у пам'яті. Порядковий номер запису дорівнює версії сцени після
+// зміни, тож клієнт, що бачив версію N, отримує пропущені зміни через Since(N).
+type ChangeLog struct {
+	// Size кількість записів, які зберігаються; 0 означає DefaultChangeLogSize.
+	Size int
+
+	mu      sync.Mutex
+	entries []JournalEntry
+	last    int
+	changed chan struct{} // закривається та замінюється після кожного запису
+}
+
+func (l *ChangeLog) append(entry JournalEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	size := l.Size
+	if size == 0 {
+		size = DefaultChangeLogSize
+	}
+	if len(l.entries) >= size {
+		l.entries = append(l.entries[:0], l.entries[len(l.entries)-size+1:]...)
+	}
+	l.entries = append(l.entries, entry)
+	l.last = entry.Seq
+	if l.changed != nil {
+		close(l.changed)
+		l.changed = nil
+	}
+}
+
+// Since повертає зміни, прийняті після версії version, та поточну версію. Якщо частину цих змін уже витіснено
+// з журналу або версія новіша за поточну (наприклад, після перезапуску), ok дорівнює false і клієнту варто заново
+// отримати сцену цілком.
+func (l *ChangeLog) Since(version int) (entries []JournalEntry, current int, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries, ok = l.since(version)
+	return entries, l.last, ok
+}
+
+func (l *ChangeLog) since(version int) ([]JournalEntry, bool) {
+	if version > l.last {
+		return nil, false
+	}
+	if version == l.last {
+		return nil, true
+	}
+	if len(l.entries) == 0 || l.entries[0].Seq > version+1 {
+		return nil, false
+	}
+	newer := l.entries[version+1-l.entries[0].Seq:]
+	return append([]JournalEntry(nil), newer...), true
+}
+
+// Wait працює як Since, але якщо змін після version ще немає, чекає на першу з них, доки не скасовано ctx.
+func (l *ChangeLog) Wait(ctx context.Context, version int) (entries []JournalEntry, current int, ok bool) {
+	for {
+		l.mu.Lock()
+		entries, ok = l.since(version)
+		current = l.last
+		if len(entries) != 0 || !ok {
+			l.mu.Unlock()
+			return entries, current, ok
+		}
+		if l.changed == nil {
+			l.changed = make(chan struct{})
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, current, true
+		}
+	}
+}
+
+// ChangesResponse відповідь ChangesHandler.
+type ChangesResponse struct {
+	Version int            `json:"version"` // поточна версія сцени
+	Changes []JournalEntry `json:"changes"` // зміни після запитаної версії; seq запису - версія сцени після нього
+}
+
+// ChangesHandler конструює обробник GET запитів /changes?since=N, який повертає зміни, прийняті після версії N.
+// Якщо нових змін ще немає, запит чекає на них до timeout секунд (типово DefaultChangesTimeout, 0 - не чекати).
+// Якщо зміни після N вже недоступні, обробник повертає 410, і клієнту варто отримати сцену через /scene.
+func ChangesHandler(changes *ChangeLog) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		query := r.URL.Query()
+		since, err := strconv.Atoi(query.Get("since"))
+		if err != nil || since < 0 {
+			http.Error(rw, "since must be a non-negative version", http.StatusBadRequest)
+			return
+		}
+		timeout := DefaultChangesTimeout
+		if s := query.Get("timeout"); s != "" {
+			seconds, err := strconv.ParseFloat(s, 64)
+			if err != nil || seconds < 0 || seconds > DefaultChangesTimeout.Seconds() {
+				http.Error(rw, "invalid timeout", http.StatusBadRequest)
+				return
+			}
+			timeout = time.Duration(seconds * float64(time.Second))
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		entries, current, ok := changes.Wait(ctx, since)
+		if !ok {
+			http.Error(rw, "changes since this version are no longer available", http.StatusGone)
+			return
+		}
+		if entries == nil {
+			entries = []JournalEntry{}
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(ChangesResponse{Version: current, Changes: entries})
+	})
+}
@@ -0,0 +1,141 @@
+package lang
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeLog_Since(t *testing.T) {
+	changes := &ChangeLog{Size: 2}
+	p := &Parser{Changes: changes}
+	for _, script := range []string{"white", "green", "figure 0.5 0.5"} {
+		_, err := p.Parse(strings.NewReader(script))
+		require.NoError(t, err)
+	}
+
+	entries, current, ok := changes.Since(1)
+	assert.True(t, ok)
+	assert.Equal(t, 3, current)
+	require.Len(t, entries, 2)
+	assert.Equal(t, 2, entries[0].Seq)
+	assert.Equal(t, "green\n", entries[0].Script)
+	assert.Equal(t, "figure 0.5 0.5\n", entries[1].Script)
+
+	entries, _, ok = changes.Since(3)
+	assert.True(t, ok)
+	assert.Empty(t, entries)
+
+	_, _, ok = changes.Since(0)
+	assert.False(t, ok, "the first change has been evicted")
+	_, _, ok = changes.Since(4)
+	assert.False(t, ok, "a version from the future is unknown")
+}
+
+func TestChangeLog_UndoIsScene(t *testing.T) {
+	changes := &ChangeLog{}
+	p := &Parser{Changes: changes}
+	for _, script := range []string{"figure 0.25 0.25", "figure 0.75 0.75", "undo"} {
+		_, err := p.Parse(strings.NewReader(script))
+		require.NoError(t, err)
+	}
+	_, err := p.Redo()
+	require.NoError(t, err)
+
+	// An observer replays the changes without access to the server's history.
+	entries, _, ok := changes.Since(0)
+	require.True(t, ok)
+	observer := &Parser{}
+	for _, entry := range entries {
+		if entry.Scene != nil {
+			_, err = observer.SetScene(*entry.Scene)
+		} else {
+			_, err = observer.Parse(strings.NewReader(entry.Script))
+		}
+		require.NoError(t, err)
+	}
+	require.Len(t, entries, 4)
+	require.NotNil(t, entries[2].Scene)
+	assert.Len(t, entries[2].Scene.Figures, 1)
+	require.NotNil(t, entries[3].Scene)
+	assert.Len(t, entries[3].Scene.Figures, 2)
+	assert.Equal(t, p.Scene(), observer.Scene())
+}
+
+func TestChangeLog_Wait(t *testing.T) {
+	changes := &ChangeLog{}
+	p := &Parser{Changes: changes}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	entries, current, ok := changes.Wait(ctx, 0)
+	assert.True(t, ok)
+	assert.Empty(t, entries)
+	assert.Equal(t, 0, current)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = p.Parse(strings.NewReader("white"))
+	}()
+	entries, current, ok = changes.Wait(context.Background(), 0)
+	assert.True(t, ok)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, 1, current)
+}
+
+func TestParser_ParseVersion(t *testing.T) {
+	p := &Parser{}
+	_, err := p.ParseVersion(0, strings.NewReader("white"))
+	require.NoError(t, err)
+
+	_, err = p.ParseVersion(0, strings.NewReader("green"))
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.Equal(t, 1, p.Version())
+}
+
+func TestChangesHandler(t *testing.T) {
+	var loop painter.Loop
+	p := &Parser{Changes: &ChangeLog{}}
+	mux := http.NewServeMux()
+	mux.Handle("/", HttpHandler(&loop, p))
+	mux.Handle("/changes", ChangesHandler(p.Changes))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/?version=0", "text/plain", strings.NewReader("white"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+"/?version=0", "text/plain", strings.NewReader("green"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/changes?since=0")
+	require.NoError(t, err)
+	var changes ChangesResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&changes))
+	resp.Body.Close()
+	assert.Equal(t, 1, changes.Version)
+	require.Len(t, changes.Changes, 1)
+	assert.Equal(t, "white\n", changes.Changes[0].Script)
+
+	resp, err = http.Get(server.URL + "/changes?since=1&timeout=0.01")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/changes?since=5")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusGone, resp.StatusCode)
+}
@@ -0,0 +1,33 @@
+package lang
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+var namedColors = map[string]color.Color{
+	"black":  color.Black,
+	"white":  color.White,
+	"red":    color.RGBA{R: 0xff, A: 0xff},
+	"green":  color.RGBA{G: 0xff, A: 0xff},
+	"blue":   color.RGBA{B: 0xff, A: 0xff},
+	"yellow": color.RGBA{R: 0xff, G: 0xff, A: 0xff},
+}
+
+// parseColor розбирає колір, заданий назвою (white, green, ...) або у форматі #rrggbb чи #rrggbbaa.
+func parseColor(s string) (color.Color, error) {
+	if c, ok := namedColors[s]; ok {
+		return c, nil
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "#"))
+	if err != nil || !strings.HasPrefix(s, "#") || (len(b) != 3 && len(b) != 4) {
+		return nil, fmt.Errorf("invalid color '%s'", s)
+	}
+	c := color.NRGBA{R: b[0], G: b[1], B: b[2], A: 0xff}
+	if len(b) == 4 {
+		c.A = b[3]
+	}
+	return c, nil
+}
@@ -0,0 +1,113 @@
+package lang
+
+import (
+	"fmt"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+)
+
+// shapeCopier робить глибокі копії фігур, зберігаючи зв'язки між ними: якщо областю відсікання є фігура,
+// копія відсікається копією цієї фігури. Кожна фігура копіюється лише раз.
+type shapeCopier map[painter.Region]painter.Shape
+
+func (c shapeCopier) shape(s painter.Shape) painter.Shape {
+	if copied, ok := c[s]; ok {
+		return copied
+	}
+	switch s := s.(type) {
+	case *painter.BackgroundRectangle:
+		rect := *s
+		c[s] = &rect
+		rect.Clip = c.region(s.Clip)
+		return &rect
+	case *painter.CrossFigure:
+		figure := *s
+		c[s] = &figure
+		figure.Clip = c.region(s.Clip)
+		return &figure
+	case *painter.Group:
+		group := *s
+		c[s] = &group
+		group.Shapes = nil
+		for _, inner := range s.Shapes {
+			group.Shapes = append(group.Shapes, c.shape(inner))
+		}
+		group.Clip = c.region(s.Clip)
+		return &group
+	}
+	panic(fmt.Sprintf("unknown shape %T", s))
+}
+
+func (c shapeCopier) region(r painter.Region) painter.Region {
+	if s, ok := r.(painter.Shape); ok {
+		return c.shape(s)
+	}
+	return r
+}
+
+func (c shapeCopier) figure(f *painter.CrossFigure) *painter.CrossFigure {
+	return c.shape(f).(*painter.CrossFigure)
+}
+
+func (c shapeCopier) group(g *painter.Group) *painter.Group {
+	return c.shape(g).(*painter.Group)
+}
+
+// operation повертає копію операції, що посилається на копії фігур.
+func (c shapeCopier) operation(op painter.Operation) painter.Operation {
+	switch op := op.(type) {
+	case painter.Shape:
+		return c.shape(op)
+	case *painter.ClipOperation:
+		return &painter.ClipOperation{Region: c.region(op.Region), Operation: c.operation(op.Operation)}
+	case *painter.MoveOperation:
+		var figures []*painter.CrossFigure
+		for _, f := range op.FiguresArray {
+			figures = append(figures, c.figure(f))
+		}
+		return &painter.MoveOperation{X: op.X, Y: op.Y, FiguresArray: figures}
+	case *painter.GroupMoveOperation:
+		return &painter.GroupMoveOperation{X: op.X, Y: op.Y, Group: c.group(op.Group)}
+	case *painter.RotateOperation:
+		return &painter.RotateOperation{Angle: op.Angle, Figure: c.figure(op.Figure)}
+	case *painter.ScaleOperation:
+		return &painter.ScaleOperation{Factor: op.Factor, Figure: c.figure(op.Figure)}
+	}
+	return op
+}
+
+// clone повертає незалежну копію стану: зміни копії не торкаються фігур і груп оригіналу.
+func (u *Uistate) clone() Uistate {
+	c := shapeCopier{}
+	next := *u
+	if u.backgroundColor != nil {
+		next.backgroundColor = c.operation(u.backgroundColor)
+	}
+	next.backgroundClip = c.region(u.backgroundClip)
+	if u.backgroundRectangle != nil {
+		next.backgroundRectangle = c.shape(u.backgroundRectangle).(*painter.BackgroundRectangle)
+	}
+	next.figuresArray = nil
+	for _, figure := range u.figuresArray {
+		next.figuresArray = append(next.figuresArray, c.figure(figure))
+	}
+	next.groups = *c.group(&u.groups)
+	next.groupsByName = nil
+	if u.groupsByName != nil {
+		next.groupsByName = make(map[string]*painter.Group, len(u.groupsByName))
+		for name, group := range u.groupsByName {
+			next.groupsByName[name] = c.group(group)
+		}
+	}
+	next.openGroups = nil
+	for _, group := range u.openGroups {
+		next.openGroups = append(next.openGroups, c.group(group))
+	}
+	next.moveOperations = nil
+	for _, op := range u.moveOperations {
+		next.moveOperations = append(next.moveOperations, c.operation(op))
+	}
+	next.transforms = append([]transform(nil), u.transforms...)
+	next.clip = c.region(u.clip)
+	return next
+}
@@ -0,0 +1,123 @@
+package lang
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+)
+
+// eventBuffer кількість подій, які може накопичити підписник; решта відкидається, щоб повільний підписник
+// не гальмував парсер.
+const eventBuffer = 64
+
+// eventKeepAlive період, з яким EventsHandler надсилає коментар, щоб проміжні проксі не закривали з'єднання.
+const eventKeepAlive = 15 * time.Second
+
+// Event подія зміни полотна.
+type Event struct {
+	Type    string    `json:"type"` // frame, accepted або rejected
+	Time    time.Time `json:"time"`
+	Version int       `json:"version"`         // версія сцени: кількість прийнятих змін від запуску
+	Frame   int       `json:"frame,omitempty"` // номер показаного кадру для frame
+	Error   string    `json:"error,omitempty"` // причина, з якої скрипт відхилено, для rejected
+}
+
+// Events розсилає події зміни полотна підписникам.
+type Events struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// Publish надсилає подію всім підписникам, не чекаючи на тих, чия черга заповнена.
+func (e *Events) Publish(ev Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for ch := range e.subscribers {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// Subscribe повертає канал подій та функцію, що скасовує підписку і закриває канал.
+func (e *Events) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventBuffer)
+	e.mu.Lock()
+	if e.subscribers == nil {
+		e.subscribers = make(map[chan Event]struct{})
+	}
+	e.subscribers[ch] = struct{}{}
+	e.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			e.mu.Lock()
+			delete(e.subscribers, ch)
+			e.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// PublishFrames підписується на кадри frames і далі публікує подію frame для кожного показаного кадру разом з
+// версією сцени, яку він показує (див. Parser.Frames). Повертає функцію, що скасовує підписку.
+func (e *Events) PublishFrames(frames *painter.FrameNotifier) func() {
+	ch, unsubscribe := frames.Subscribe()
+	go func() {
+		for frame := range ch {
+			e.Publish(Event{Type: "frame", Time: frame.Time, Version: frame.Version, Frame: frame.Number})
+		}
+	}()
+	return unsubscribe
+}
+
+// EventsHandler конструює обробник Server-Sent Events: клієнт отримує кожну подію з events як подію SSE
+// з назвою типу події та JSON описом у полі data.
+func EventsHandler(events *Events) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := rw.(http.Flusher)
+		if !ok {
+			http.Error(rw, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		ch, unsubscribe := events.Subscribe()
+		defer unsubscribe()
+		rw.Header().Set("Content-Type", "text/event-stream")
+		rw.Header().Set("Cache-Control", "no-cache")
+		rw.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(eventKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case ev := <-ch:
+				data, err := json.Marshal(ev)
+				if err != nil {
+					return
+				}
+				if _, err := fmt.Fprintf(rw, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(rw, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			}
+			flusher.Flush()
+		}
+	})
+}
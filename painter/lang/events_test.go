@@ -0,0 +1,76 @@
+package lang
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsHandler(t *testing.T) {
+	events := &Events{}
+	frames := &painter.FrameNotifier{}
+	parser := &Parser{Events: events, Frames: frames}
+	defer events.PublishFrames(frames)()
+	server := httptest.NewServer(EventsHandler(events))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	scanner := bufio.NewScanner(resp.Body)
+	next := func() (string, Event) {
+		var name string
+		var ev Event
+		for scanner.Scan() {
+			line := scanner.Text()
+			if n, ok := strings.CutPrefix(line, "event: "); ok {
+				name = n
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				require.NoError(t, json.Unmarshal([]byte(data), &ev))
+				return name, ev
+			}
+		}
+		t.Fatal("event stream ended")
+		return "", ev
+	}
+
+	ops, err := parser.Parse(strings.NewReader("white"))
+	require.NoError(t, err)
+	name, ev := next()
+	assert.Equal(t, "accepted", name)
+	assert.Equal(t, 1, ev.Version)
+
+	_, err = parser.Parse(strings.NewReader("bad"))
+	require.Error(t, err)
+	_, ev = next()
+	assert.Equal(t, Event{Type: "rejected", Time: ev.Time, Version: 1, Error: "line 1: invalid command bad"}, ev)
+
+	// A frame carries the version of the scene it shows, not the latest accepted one.
+	frames.Update(nil)
+	_, ev = next()
+	assert.Equal(t, "frame", ev.Type)
+	assert.Equal(t, 1, ev.Frame)
+	assert.Equal(t, 0, ev.Version)
+
+	painter.OperationList(ops).Do(painter.NewImageTexture(image.Pt(800, 800)))
+	frames.Update(nil)
+	_, ev = next()
+	assert.Equal(t, 2, ev.Frame)
+	assert.Equal(t, 1, ev.Version)
+}
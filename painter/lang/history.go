@@ -0,0 +1,57 @@
+package lang
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// historySize найбільша кількість станів сцени, які зберігає історія.
+const historySize = 100
+
+// history обмежена історія станів сцени для команд undo та redo.
+type history struct {
+	states   []Scene
+	position int // індекс поточного стану в states
+}
+
+// HistoryState описує позицію поточного стану сцени в історії.
+type HistoryState struct {
+	Position int `json:"position"`
+	Length   int `json:"length"`
+}
+
+// record додає новий стан сцени, відкидаючи стани, скасовані раніше. Стан, що не відрізняється від поточного,
+// не записується.
+func (h *history) record(s Scene) {
+	if len(h.states) != 0 && reflect.DeepEqual(h.states[h.position], s) {
+		return
+	}
+	if len(h.states) != 0 {
+		h.states = h.states[:h.position+1]
+	}
+	h.states = append(h.states, s)
+	if len(h.states) > historySize {
+		h.states = h.states[len(h.states)-historySize:]
+	}
+	h.position = len(h.states) - 1
+}
+
+func (h *history) undo() (Scene, error) {
+	if h.position == 0 {
+		return Scene{}, fmt.Errorf("nothing to undo")
+	}
+	h.position--
+	return h.states[h.position], nil
+}
+
+func (h *history) redo() (Scene, error) {
+	if h.position+1 >= len(h.states) {
+		return Scene{}, fmt.Errorf("nothing to redo")
+	}
+	h.position++
+	return h.states[h.position], nil
+}
+
+func (h *history) state() HistoryState {
+	return HistoryState{Position: h.position, Length: len(h.states)}
+}
@@ -0,0 +1,43 @@
+package lang
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_UndoRedo(t *testing.T) {
+	parser := &Parser{}
+	parse := func(script string) {
+		_, err := parser.Parse(strings.NewReader(script))
+		require.NoError(t, err)
+	}
+
+	parse("figure 0.5 0.5")
+	parse("move 0.125 0")
+	parse("update")
+	assert.Equal(t, HistoryState{Position: 2, Length: 3}, parser.History())
+
+	ops, err := parser.Undo()
+	require.NoError(t, err)
+	assert.Equal(t, painter.UpdateOp, ops[len(ops)-1])
+	assert.Equal(t, &ScenePoint{X: 400, Y: 400}, parser.Scene().Figures[0].Center)
+
+	_, err = parser.Redo()
+	require.NoError(t, err)
+	assert.Equal(t, &ScenePoint{X: 500, Y: 400}, parser.Scene().Figures[0].Center)
+	_, err = parser.Redo()
+	assert.Error(t, err)
+
+	parse("undo\nundo")
+	assert.Empty(t, parser.Scene().Figures)
+	_, err = parser.Undo()
+	assert.Error(t, err)
+
+	// A new change discards the states that could be redone.
+	parse("green")
+	assert.Equal(t, HistoryState{Position: 1, Length: 2}, parser.History())
+}
@@ -1,27 +1,50 @@
 package lang
 
 import (
+	"encoding/json"
+	"errors"
+	"image/png"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/NikitaSutulov/software-architecture-lab3/painter"
 )
 
 // HttpHandler конструює обробник HTTP запитів, який дані з запиту віддає у Parser, а потім відправляє отриманий список
-// операцій у painter.Loop.
+// операцій у painter.Loop. Якщо запит містить параметр version, скрипт виконується лише для цієї версії сцени,
+// а інакше обробник повертає 409.
 func HttpHandler(loop *painter.Loop, p *Parser) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
 		var in io.Reader = r.Body
 		if r.Method == http.MethodGet {
-			in = strings.NewReader(r.URL.Query().Get("cmd"))
+			in = strings.NewReader(query.Get("cmd"))
 		}
 
-		cmds, err := p.Parse(in)
+		var (
+			cmds []painter.Operation
+			err  error
+		)
+		if query.Has("version") {
+			version, convErr := strconv.Atoi(query.Get("version"))
+			if convErr != nil {
+				http.Error(rw, "version must be an integer", http.StatusBadRequest)
+				return
+			}
+			cmds, err = p.ParseVersion(version, in)
+		} else {
+			cmds, err = p.Parse(in)
+		}
+		if errors.Is(err, ErrConflict) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
 		if err != nil {
 			log.Printf("Bad script: %s", err)
-			rw.WriteHeader(http.StatusBadRequest)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
 			return
 		}
 
@@ -29,3 +52,134 @@ func HttpHandler(loop *painter.Loop, p *Parser) http.Handler {
 		rw.WriteHeader(http.StatusOK)
 	})
 }
+
+// SceneHandler конструює обробник HTTP запитів до документа сцени: GET повертає поточний стан сцени у форматі JSON,
+// а PUT атомарно замінює його та відправляє операції перемальовування у painter.Loop.
+func SceneHandler(loop *painter.Loop, p *Parser) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			rw.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(rw).Encode(p.Scene())
+		case http.MethodPut:
+			var scene Scene
+			if err := json.NewDecoder(r.Body).Decode(&scene); err != nil {
+				log.Printf("Bad scene: %s", err)
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			ops, err := p.SetScene(scene)
+			if err != nil {
+				log.Printf("Bad scene: %s", err)
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			loop.Post(painter.OperationList(ops))
+			rw.WriteHeader(http.StatusOK)
+		default:
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// SVGHandler конструює обробник HTTP запитів, який повертає поточну сцену у форматі SVG.
+func SVGHandler(p *Parser) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		rw.Header().Set("Content-Type", "image/svg+xml")
+		if err := WriteSVG(rw, p.Scene()); err != nil {
+			log.Printf("Failed to write SVG: %s", err)
+		}
+	})
+}
+
+// SnapshotHandler конструює обробник HTTP запитів, який повертає поточну сцену, намальовану у пам'яті, як PNG.
+func SnapshotHandler(p *Parser) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		img, err := Render(p.Scene())
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "image/png")
+		if err := png.Encode(rw, img); err != nil {
+			log.Printf("Failed to write snapshot: %s", err)
+		}
+	})
+}
+
+// HistoryHandler конструює обробник HTTP запитів до історії сцени: POST /undo та POST /redo відновлюють попередній
+// або скасований стан сцени і перемальовують її, а GET /history лише повертає позицію в історії.
+func HistoryHandler(loop *painter.Loop, p *Parser) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var step func() ([]painter.Operation, error)
+		switch {
+		case r.URL.Path == "/undo" && r.Method == http.MethodPost:
+			step = p.Undo
+		case r.URL.Path == "/redo" && r.Method == http.MethodPost:
+			step = p.Redo
+		case r.URL.Path == "/history" && r.Method == http.MethodGet:
+		default:
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if step != nil {
+			ops, err := step()
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusConflict)
+				return
+			}
+			loop.Post(painter.OperationList(ops))
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(p.History())
+	})
+}
+
+// RecordHandler конструює обробник HTTP запитів, що керують записом анімації: POST /record/start?file=<file> починає
+// запис, а POST /record/stop завершує його. Обидві дії виконуються у циклі подій, тож запис охоплює кадри, показані
+// між запитами; помилка запису повертається зі статусом 409. Файл розміщується у каталозі p.Dir, як і файли команди
+// record start.
+func RecordHandler(loop *painter.Loop, p *Parser) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		done := make(chan error, 1)
+		op := &painter.RecordOperation{Recorder: p.Recorder, Done: done}
+		switch r.URL.Path {
+		case "/record/start":
+			name := r.URL.Query().Get("file")
+			if name == "" {
+				http.Error(rw, "no file to record to", http.StatusBadRequest)
+				return
+			}
+			path, err := p.scenePath(name)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			op.Path = path
+		case "/record/stop":
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		loop.Post(op)
+		if err := <-done; err != nil {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	})
+}
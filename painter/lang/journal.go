@@ -0,0 +1,225 @@
+package lang
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+)
+
+// DefaultCompactAfter кількість записів журналу, після якої він стискається у знімок сцени.
+const DefaultCompactAfter = 1000
+
+// JournalEntry запис журналу: прийнятий скрипт або знімок сцени.
+type JournalEntry struct {
+	Seq    int       `json:"seq"`
+	Time   time.Time `json:"time"`
+	Script string    `json:"script,omitempty"`
+	Scene  *Scene    `json:"scene,omitempty"`
+}
+
+// Journal журнал, у кінець якого дописується кожен прийнятий скрипт. Після перезапуску журнал можна відтворити
+// через Parser та painter.Loop, щоб відновити останній стан полотна.
+type Journal struct {
+	// CompactAfter кількість записів, після якої журнал замінюється одним знімком сцени; 0 означає DefaultCompactAfter.
+	CompactAfter int
+
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	size    int64 // довжина файлу, що містить лише повні записи
+	seq     int
+	entries int
+}
+
+// OpenJournal відкриває файл журналу, створюючи його за потреби. Незавершений останній запис, що залишився після
+// збою під час запису, відкидається.
+func OpenJournal(path string) (*Journal, error) {
+	entries, size, err := readJournal(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err == nil && info.Size() > size {
+		log.Printf("Journal %s: dropping an incomplete last record", path)
+		err = f.Truncate(size)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	j := &Journal{path: path, f: f, size: size, entries: len(entries)}
+	if len(entries) != 0 {
+		j.seq = entries[len(entries)-1].Seq
+	}
+	return j, nil
+}
+
+// readJournal читає записи журналу та повертає їх разом з довжиною частини файлу, що містить повні записи.
+// Останній рядок без переведення рядка або з некоректним JSON - запис, який не встиг дописатися, тож він
+// пропускається; пошкоджений запис посередині файлу є помилкою.
+func readJournal(path string) ([]JournalEntry, int64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var (
+		entries []JournalEntry
+		size    int64
+	)
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		data, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return entries, size, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			if _, peekErr := r.Peek(1); peekErr == io.EOF {
+				return entries, size, nil
+			}
+			return nil, 0, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+		size += int64(len(data))
+	}
+}
+
+// Replay відтворює записи журналу: скрипти розбираються парсером, знімки замінюють сцену, а отримані операції
+// передаються у цикл подій. Наприкінці полотно оновлюється. Під час відтворення парсер не журналює змін, тому
+// Replay слід викликати до того, як парсер почне приймати інші запити. Цикл подій можна ще не запускати: операції
+// чекатимуть у черзі.
+func (j *Journal) Replay(p *Parser, loop *painter.Loop) error {
+	entries, _, err := readJournal(j.path)
+	if err != nil {
+		return err
+	}
+	defer p.startReplay()()
+
+	for _, entry := range entries {
+		var ops []painter.Operation
+		if entry.Scene != nil {
+			ops, err = p.SetScene(*entry.Scene)
+		} else {
+			ops, err = p.Parse(strings.NewReader(entry.Script))
+		}
+		if err != nil {
+			return fmt.Errorf("journal entry %d: %w", entry.Seq, err)
+		}
+		loop.Post(painter.OperationList(ops))
+	}
+	loop.Post(painter.UpdateOp)
+	return nil
+}
+
+// Append дописує скрипт у журнал.
+func (j *Journal) Append(script string) error {
+	return j.append(JournalEntry{Script: script})
+}
+
+// AppendScene дописує у журнал знімок сцени, яким її було замінено.
+func (j *Journal) AppendScene(scene Scene) error {
+	return j.append(JournalEntry{Scene: &scene})
+}
+
+func (j *Journal) append(entry JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry.Seq, entry.Time = j.seq+1, time.Now()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := j.f.Write(data); err != nil {
+		// Частково записаний рядок зіпсував би наступні записи.
+		_ = j.f.Truncate(j.size)
+		return err
+	}
+	j.size += int64(len(data))
+	j.seq++
+	j.entries++
+	return j.f.Sync()
+}
+
+// NeedsCompaction повідомляє, чи час замінити журнал знімком сцени.
+func (j *Journal) NeedsCompaction() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	limit := j.CompactAfter
+	if limit == 0 {
+		limit = DefaultCompactAfter
+	}
+	return j.entries > limit
+}
+
+// Compact атомарно замінює вміст журналу одним знімком сцени з наступним порядковим номером.
+func (j *Journal) Compact(scene Scene) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry := JournalEntry{Seq: j.seq + 1, Time: time.Now(), Scene: &scene}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	data = append(data, '\n')
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), j.path); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	j.f.Close()
+	j.f, j.size, j.seq, j.entries = f, int64(len(data)), entry.Seq, 1
+	return nil
+}
+
+// Close закриває файл журналу.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.f.Close()
+}
@@ -0,0 +1,174 @@
+package lang
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal_Replay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	journal, err := OpenJournal(path)
+	require.NoError(t, err)
+
+	parser := &Parser{Journal: journal}
+	_, err = parser.Parse(strings.NewReader("green\nfigure 0.5 0.5\n\nupdate"))
+	require.NoError(t, err)
+	_, err = parser.Parse(strings.NewReader("bad"))
+	require.Error(t, err)
+	_, err = parser.SetScene(Scene{Version: SceneVersion, Figures: []SceneShape{{Type: "figure", Center: &ScenePoint{X: 100, Y: 100}}}})
+	require.NoError(t, err)
+	_, err = parser.Undo()
+	require.NoError(t, err)
+	require.NoError(t, journal.Close())
+
+	entries, _, err := readJournal(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "green\nfigure 0.5 0.5\nupdate\n", entries[0].Script)
+	assert.NotNil(t, entries[1].Scene)
+	// Undo depends on the history, which is gone after a restart, so the resulting scene is journaled.
+	require.NotNil(t, entries[2].Scene)
+	assert.Len(t, entries[2].Scene.Figures, 1)
+	for i, entry := range entries {
+		assert.Equal(t, i+1, entry.Seq)
+	}
+
+	// Replaying into a fresh parser restores the scene without writing to the journal again.
+	journal, err = OpenJournal(path)
+	require.NoError(t, err)
+	defer journal.Close()
+	restored := &Parser{Journal: journal}
+	var loop painter.Loop
+	require.NoError(t, journal.Replay(restored, &loop))
+	assert.Equal(t, parser.Scene(), restored.Scene())
+	assert.Equal(t, painter.UpdateOp, loop.Mq.Ops[len(loop.Mq.Ops)-1])
+
+	entries, _, err = readJournal(path)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
+
+func TestJournal_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	journal, err := OpenJournal(path)
+	require.NoError(t, err)
+	defer journal.Close()
+	journal.CompactAfter = 2
+
+	parser := &Parser{Journal: journal}
+	for _, script := range []string{"white", "figure 0.5 0.5", "bgrect 0 0 0.25 0.25"} {
+		_, err := parser.Parse(strings.NewReader(script))
+		require.NoError(t, err)
+	}
+
+	entries, _, err := readJournal(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 4, entries[0].Seq)
+	require.NotNil(t, entries[0].Scene)
+	assert.Equal(t, parser.Scene(), *entries[0].Scene)
+
+	// Appending continues after the snapshot.
+	_, err = parser.Parse(strings.NewReader("green"))
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+}
+
+func TestJournal_ReplayUndoAfterCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	journal, err := OpenJournal(path)
+	require.NoError(t, err)
+	journal.CompactAfter = 2
+
+	parser := &Parser{Journal: journal}
+	for _, script := range []string{"figure 0.1 0.1", "figure 0.2 0.2", "figure 0.3 0.3", "undo"} {
+		_, err := parser.Parse(strings.NewReader(script))
+		require.NoError(t, err)
+	}
+	_, err = parser.Redo()
+	require.NoError(t, err)
+	_, err = parser.Undo()
+	require.NoError(t, err)
+	require.Len(t, parser.Scene().Figures, 2)
+	require.NoError(t, journal.Close())
+
+	// After a restart the history that undo and redo referred to is gone.
+	journal, err = OpenJournal(path)
+	require.NoError(t, err)
+	defer journal.Close()
+	restored := &Parser{Journal: journal}
+	var loop painter.Loop
+	require.NoError(t, journal.Replay(restored, &loop))
+	assert.Equal(t, parser.Scene(), restored.Scene())
+}
+
+func TestJournal_TornLastRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	journal, err := OpenJournal(path)
+	require.NoError(t, err)
+	parser := &Parser{Journal: journal}
+	_, err = parser.Parse(strings.NewReader("figure 0.5 0.5"))
+	require.NoError(t, err)
+	require.NoError(t, journal.Close())
+
+	// A crash in the middle of Append leaves half a record at the end of the file.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"seq":2,"time":"2026-`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	journal, err = OpenJournal(path)
+	require.NoError(t, err)
+	restored := &Parser{Journal: journal}
+	var loop painter.Loop
+	require.NoError(t, journal.Replay(restored, &loop))
+	assert.Len(t, restored.Scene().Figures, 1)
+
+	// New records follow the last complete one.
+	_, err = restored.Parse(strings.NewReader("green"))
+	require.NoError(t, err)
+	require.NoError(t, journal.Close())
+	entries, _, err := readJournal(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, 2, entries[1].Seq)
+
+	// Damage anywhere else is an error.
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, append([]byte("{\n"), data...), 0644))
+	_, err = OpenJournal(path)
+	assert.Error(t, err)
+}
+
+func TestJournal_ReplayTransformAfterCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	journal, err := OpenJournal(path)
+	require.NoError(t, err)
+	journal.CompactAfter = 2
+
+	parser := &Parser{Journal: journal}
+	for _, script := range []string{"translate 0.25 0.25", "figure 0 0", "figure 0.1 0.1", "figure 0.2 0.2"} {
+		_, err := parser.Parse(strings.NewReader(script))
+		require.NoError(t, err)
+	}
+	require.NoError(t, journal.Close())
+
+	journal, err = OpenJournal(path)
+	require.NoError(t, err)
+	defer journal.Close()
+	restored := &Parser{Journal: journal}
+	var loop painter.Loop
+	require.NoError(t, journal.Replay(restored, &loop))
+	assert.Equal(t, parser.Scene(), restored.Scene())
+	assert.Equal(t, &ScenePoint{X: 160, Y: 160}, restored.Scene().Figures[2].Center)
+}
@@ -0,0 +1,190 @@
+package lang
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxIdleBuckets кількість відер, після якої з пам'яті видаляються відра, що вже встигли наповнитися.
+const maxIdleBuckets = 1024
+
+// Limits обмежує запити до HTTP API, щоб один клієнт не міг перевантажити цикл подій. Нульові поля вимикають
+// відповідні обмеження.
+type Limits struct {
+	MaxBodySize int64   // найбільший розмір тіла запиту у байтах
+	MaxCommands int     // найбільша кількість команд у скрипті
+	Rate        float64 // кількість запитів на секунду для одного клієнта
+	Burst       int     // кількість запитів, які клієнт може надіслати одразу; 0 означає 1
+	// KeyByToken рахує запити за bearer токеном замість адреси клієнта, якщо Auth знає цей токен. Запити з
+	// невідомими токенами рахуються за адресою, щоб клієнт не міг отримувати нове відро для кожного вигаданого токена.
+	KeyByToken bool
+	// Auth токени, за якими можна рахувати запити з KeyByToken; nil означає, що запити рахуються за адресою.
+	Auth *Auth
+	// Now повертає поточний час; nil означає time.Now.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// bucket відро токенів одного клієнта.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Handler обгортає обробник обмеженням частоти запитів (429 з заголовком Retry-After) та розміру тіла запиту (413).
+func (l *Limits) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if wait, ok := l.allow(l.key(r)); !ok {
+			rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(rw, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		if l.MaxBodySize > 0 {
+			if r.ContentLength > l.MaxBodySize {
+				http.Error(rw, "request body is too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, l.MaxBodySize))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(rw, "request body is too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		h.ServeHTTP(rw, r)
+	})
+}
+
+// CommandLimit обгортає обробник скриптів (HttpHandler) обмеженням кількості команд у скрипті (413).
+func (l *Limits) CommandLimit(h http.Handler) http.Handler {
+	if l.MaxCommands <= 0 {
+		return h
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		script := r.URL.Query().Get("cmd")
+		if r.Method != http.MethodGet {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			script = string(body)
+		}
+
+		if commands := countCommands(script); commands > l.MaxCommands {
+			http.Error(rw, fmt.Sprintf("script has %d commands, at most %d are allowed", commands, l.MaxCommands),
+				http.StatusRequestEntityTooLarge)
+			return
+		}
+		h.ServeHTTP(rw, r)
+	})
+}
+
+// checkScript перевіряє скрипт, що надійшов не окремим HTTP запитом (наприклад, через WebSocket): кількість команд
+// у ньому та частоту скриптів клієнта, що надіслав запит r. Нульовий вказівник на Limits нічого не обмежує.
+func (l *Limits) checkScript(r *http.Request, script string) error {
+	if l == nil {
+		return nil
+	}
+	if commands := countCommands(script); l.MaxCommands > 0 && commands > l.MaxCommands {
+		return fmt.Errorf("script has %d commands, at most %d are allowed", commands, l.MaxCommands)
+	}
+	if wait, ok := l.allow(l.key(r)); !ok {
+		return fmt.Errorf("too many requests, retry in %s", wait.Round(time.Second))
+	}
+	return nil
+}
+
+// countCommands повертає кількість непорожніх рядків скрипту.
+func countCommands(script string) int {
+	commands := 0
+	for _, line := range strings.Split(script, "\n") {
+		if strings.TrimSpace(line) != "" {
+			commands++
+		}
+	}
+	return commands
+}
+
+// key повертає ідентифікатор клієнта, для якого рахуються запити.
+func (l *Limits) key(r *http.Request) string {
+	if l.KeyByToken && l.Auth != nil {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token = strings.TrimSpace(token); ok && l.Auth.permission(token) != 0 {
+			return "token " + token
+		}
+	}
+	return remoteHost(r.RemoteAddr)
+}
+
+// remoteHost повертає адресу клієнта без порту.
+func remoteHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// allow забирає токен з відра клієнта; якщо відро порожнє, повертає час до появи наступного токена.
+func (l *Limits) allow(key string) (time.Duration, bool) {
+	if l.Rate <= 0 {
+		return 0, true
+	}
+	burst := float64(l.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	now := time.Now()
+	if l.Now != nil {
+		now = l.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.buckets == nil {
+		l.buckets = make(map[string]*bucket)
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxIdleBuckets {
+			l.forgetIdle(now, burst)
+		}
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.Rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// forgetIdle видаляє відра, які вже встигли наповнитися: для таких клієнтів нове відро нічим не відрізняється.
+func (l *Limits) forgetIdle(now time.Time, burst float64) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.Rate >= burst {
+			delete(l.buckets, key)
+		}
+	}
+}
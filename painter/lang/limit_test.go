@@ -0,0 +1,70 @@
+package lang
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits_Handler(t *testing.T) {
+	now := time.Unix(0, 0)
+	limits := &Limits{MaxBodySize: 16, MaxCommands: 2, Rate: 2, Burst: 2, Now: func() time.Time { return now }}
+	var received string
+	handler := limits.Handler(limits.CommandLimit(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+	})))
+	request := func(addr, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.RemoteAddr = addr
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, r)
+		return rw
+	}
+
+	assert.Equal(t, http.StatusOK, request("10.0.0.1:1000", "white\nupdate").Code)
+	assert.Equal(t, "white\nupdate", received)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, request("10.0.0.1:1001", "white\nupdate\nupdate").Code)
+
+	// The burst is spent; another address still has its own bucket.
+	rw := request("10.0.0.1:1002", "white")
+	assert.Equal(t, http.StatusTooManyRequests, rw.Code)
+	assert.Equal(t, "1", rw.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, request("10.0.0.2:1000", "white").Code)
+
+	now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, request("10.0.0.1:1003", strings.Repeat("x", 17)).Code)
+}
+
+func TestLimits_KeyByToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(path, []byte("artist draw\n"), 0600))
+	auth, err := LoadTokens(path)
+	require.NoError(t, err)
+	limits := &Limits{Rate: 1, Burst: 1, KeyByToken: true, Auth: auth, Now: func() time.Time { return time.Unix(0, 0) }}
+	handler := limits.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	request := func(addr, token string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = addr
+		r.Header.Set("Authorization", "Bearer "+token)
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, r)
+		return rw.Code
+	}
+
+	// A known token has one bucket wherever the requests come from.
+	assert.Equal(t, http.StatusOK, request("10.0.0.1:1000", "artist"))
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.2:1000", "artist"))
+
+	// Made-up tokens do not get fresh buckets.
+	assert.Equal(t, http.StatusOK, request("10.0.0.3:1000", "guest1"))
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.3:1000", "guest2"))
+}
@@ -0,0 +1,103 @@
+package lang
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+)
+
+// hatchLineWidth товщина ліній штриховки у пікселях.
+const hatchLineWidth = 2
+
+// parsePaint розбирає опис заливки, який іде після команд fill та paint:
+//
+//	<color>
+//	linear x1 y1 x2 y2 <color> <color>
+//	radial cx cy r <color> <color>
+//	checker size <color> <color>
+//	stripes width angle <color> <color>
+//	hatch spacing angle <color> <color>
+func parsePaint(command string, spec []string) (painter.Paint, error) {
+	if len(spec) == 0 {
+		return nil, fmt.Errorf("missing fill for '%s' command", command)
+	}
+	if len(spec) == 1 {
+		c, err := parseColor(spec[0])
+		if err != nil {
+			return nil, err
+		}
+		return painter.Solid{Color: c}, nil
+	}
+
+	kind := spec[0]
+	var numbers int
+	switch kind {
+	case "linear":
+		numbers = 4
+	case "radial":
+		numbers = 3
+	case "checker":
+		numbers = 1
+	case "stripes", "hatch":
+		numbers = 2
+	default:
+		return nil, fmt.Errorf("unknown fill '%s' for '%s' command", kind, command)
+	}
+	if len(spec) != numbers+3 {
+		return nil, fmt.Errorf("wrong number of arguments for '%s' fill", kind)
+	}
+	first, err := parseColor(spec[numbers+1])
+	if err != nil {
+		return nil, err
+	}
+	second, err := parseColor(spec[numbers+2])
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "linear":
+		params, err := checkForErrorsInParameters(spec[:5], 5)
+		if err != nil {
+			return nil, err
+		}
+		return painter.LinearGradient{
+			From:      image.Pt(params[0], params[1]),
+			To:        image.Pt(params[2], params[3]),
+			FromColor: first,
+			ToColor:   second,
+		}, nil
+	case "radial":
+		params, err := checkForErrorsInParameters(spec[:4], 4)
+		if err != nil {
+			return nil, err
+		}
+		return painter.RadialGradient{Center: image.Pt(params[0], params[1]), Radius: params[2], Inner: first, Outer: second}, nil
+	case "checker":
+		params, err := checkForErrorsInParameters(spec[:2], 2)
+		if err != nil {
+			return nil, err
+		}
+		if params[0] <= 0 {
+			return nil, fmt.Errorf("checker size must be positive")
+		}
+		return painter.Checkerboard{Size: params[0], A: first, B: second}, nil
+	}
+
+	params, err := checkForErrorsInParameters(spec[:2], 2)
+	if err != nil {
+		return nil, err
+	}
+	if params[0] <= 0 {
+		return nil, fmt.Errorf("%s spacing must be positive", kind)
+	}
+	angle, err := parseFloatParameter(kind, spec[2])
+	if err != nil {
+		return nil, err
+	}
+	if kind == "stripes" {
+		return painter.Stripes{Width: params[0], Angle: angle, A: first, B: second}, nil
+	}
+	return painter.Hatching{Spacing: params[0], LineWidth: hatchLineWidth, Angle: angle, Line: first, Background: second}, nil
+}
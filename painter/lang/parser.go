@@ -5,39 +5,318 @@ import (
 	"fmt"
 	"image"
 	"io"
+	"log"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/NikitaSutulov/software-architecture-lab3/painter"
 )
 
+// Commands назви команд мови скриптів, наприклад для автодоповнення у клієнтах. TestCommands перевіряє, що список
+// збігається з командами, які розбирає parse.
+var Commands = []string{
+	"white", "green", "fill", "paint", "bgrect", "figure",
+	"push", "pop", "translate", "rotate", "scale", "move",
+	"group", "hide", "show", "color", "delete", "clip", "unclip",
+	"save", "load", "record", "undo", "redo", "reset", "update",
+}
+
 // Parser уміє прочитати дані з вхідного io.Reader та повернути список операцій представлені вхідним скриптом.
 type Parser struct {
-	uistate Uistate
+	// Journal журнал, у який дописуються прийняті скрипти та заміни сцени; nil вимикає журналювання.
+	Journal *Journal
+	// Recorder записує анімацію між командами record start та record stop; nil вимикає ці команди.
+	Recorder *painter.GIFRecorder
+	// Events отримує події про прийняті та відхилені скрипти; nil вимикає події.
+	Events *Events
+	// Changes зберігає останні прийняті зміни для клієнтів, що стежать за полотном; nil вимикає журнал змін.
+	Changes *ChangeLog
+	// Frames отримує версію сцени разом з операціями, що її малюють, щоб кожен кадр знав версію, яку показує; nil
+	// вимикає позначення кадрів.
+	Frames *painter.FrameNotifier
+	// Session записує кожну прийняту зміну з часом її надходження, щоб сесію можна було відтворити; nil вимикає запис.
+	Session *Recorder
+	// Dir каталог, у якому команди save та load записують і читають файли сцен, а record start - анімацію; порожній
+	// вимикає ці команди.
+	Dir string
+
+	mu        sync.Mutex
+	uistate   Uistate
+	history   history
+	version   int  // кількість прийнятих змін сцени
+	replaying bool // під час відтворення зміни не журналюються і не записуються, а команди save та record не виконуються
+	// restored позначає скрипт з командами undo, redo або load: результат залежить від історії чи файлу,
+	// тож замість тексту скрипту журналюється отримана сцена.
+	restored bool
+	before   checkpoint    // стан до початку скрипту, до якого повертається відхилений скрипт
+	saves    []pendingSave // сцени, які команди save запишуть, коли скрипт буде прийнято
+}
+
+// checkpoint стан парсера, до якого можна повернутися.
+type checkpoint struct {
+	uistate  Uistate
+	position int // позиція в історії
+}
+
+// pendingSave сцена, яку треба записати у файл path.
+type pendingSave struct {
+	path  string
+	scene Scene
 }
 
 func (p *Parser) Parse(in io.Reader) ([]painter.Operation, error) {
-	p.uistate.ResetOperations()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.parseScript(in)
+}
+
+// ParseVersion виконує скрипт, лише якщо поточна версія сцени дорівнює version, тобто клієнт бачив усі прийняті
+// зміни. Інакше скрипт відхиляється з помилкою ErrConflict.
+func (p *Parser) ParseVersion(version int, in io.Reader) ([]painter.Operation, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.version != version {
+		return nil, p.reject(fmt.Errorf("%w: expected version %d, current version is %d", ErrConflict, version, p.version))
+	}
+	return p.parseScript(in)
+}
+
+// parseScript виконує скрипт як одне ціле: якщо якусь команду відхилено, сцена повертається до стану перед скриптом.
+func (p *Parser) parseScript(in io.Reader) ([]painter.Operation, error) {
+	p.begin()
 
 	scanner := bufio.NewScanner(in)
 	scanner.Split(bufio.ScanLines)
 
-	for scanner.Scan() {
+	var script strings.Builder
+	for line := 1; scanner.Scan(); line++ {
 		cmdl := scanner.Text()
+		if strings.TrimSpace(cmdl) == "" {
+			continue
+		}
 
 		err := p.parse(cmdl)
 		if err != nil {
-			return nil, err
+			return nil, p.rollback(fmt.Errorf("line %d: %w", line, err))
+		}
+		script.WriteString(cmdl + "\n")
+	}
+	return p.finish(script.String())
+}
+
+// begin готує стан до виконання нового скрипту. Перетворення та відсікання попереднього скрипту скидаються.
+func (p *Parser) begin() {
+	p.uistate.ResetOperations()
+	p.uistate.ResetDrawing()
+	p.recordInitialState()
+	p.restored = false
+	p.saves = nil
+	p.before = checkpoint{uistate: p.uistate.clone(), position: p.history.position}
+}
+
+// staging повертає окремий парсер з копією сцени та історії p, на якому можна виконувати команди, не змінюючи p.
+// Такий парсер нічого не журналює та не записує файлів. Викликається під p.mu.
+func (p *Parser) staging() *Parser {
+	return &Parser{
+		Recorder: p.Recorder,
+		Dir:      p.Dir,
+		uistate:  p.uistate.clone(),
+		history:  history{states: append([]Scene(nil), p.history.states...), position: p.history.position},
+		version:  p.version,
+	}
+}
+
+// rollback повертає стан, збережений на початку скрипту, та відхиляє скрипт з причиною err.
+func (p *Parser) rollback(err error) error {
+	p.uistate = p.before.uistate
+	p.history.position = p.before.position
+	p.saves = nil
+	return p.reject(err)
+}
+
+// finish завершує виконання скрипту script: записує зміну в історію та журнал і повертає операції,
+// що малюють сцену.
+func (p *Parser) finish(script string) ([]painter.Operation, error) {
+	if err := p.uistate.CheckGroupsClosed(); err != nil {
+		return nil, p.rollback(err)
+	}
+	// Файли записуються лише тепер, коли відомо, що скрипт буде прийнято.
+	if !p.replaying {
+		for _, save := range p.saves {
+			if err := saveScene(save.path, save.scene); err != nil {
+				return nil, p.rollback(err)
+			}
+		}
+	}
+	p.saves = nil
+	scene := p.uistate.Scene()
+	p.history.record(scene)
+	if p.restored {
+		p.accept(JournalEntry{Scene: &scene})
+	} else {
+		p.accept(JournalEntry{Script: script})
+	}
+
+	return p.operations(), nil
+}
+
+// operations повертає операції, що малюють сцену; якщо задано Frames, вони позначають кадри поточною версією сцени.
+func (p *Parser) operations() []painter.Operation {
+	ops := p.uistate.GetOperations()
+	if p.Frames != nil {
+		ops = append([]painter.Operation{p.Frames.SceneVersion(p.version)}, ops...)
+	}
+	return ops
+}
+
+// Scene повертає документ з поточним станом сцени.
+func (p *Parser) Scene() Scene {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.uistate.Scene()
+}
+
+// SetScene атомарно замінює стан сцени документом і повертає операції, що малюють та показують нову сцену.
+func (p *Parser) SetScene(scene Scene) ([]painter.Operation, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.recordInitialState()
+	if err := p.uistate.SetScene(scene); err != nil {
+		return nil, err
+	}
+	p.history.record(p.uistate.Scene())
+	p.accept(JournalEntry{Scene: &scene})
+	p.uistate.SetUpdateOperation()
+	return p.operations(), nil
+}
+
+// Undo повертає сцену до попереднього стану в історії та повертає операції, що її перемальовують.
+func (p *Parser) Undo() ([]painter.Operation, error) {
+	return p.restore(p.undo)
+}
+
+// Redo повторює скасовану зміну сцени та повертає операції, що її перемальовують.
+func (p *Parser) Redo() ([]painter.Operation, error) {
+	return p.restore(p.redo)
+}
+
+// History повертає позицію поточного стану сцени в історії.
+func (p *Parser) History() HistoryState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.history.state()
+}
+
+// restore виконує крок історії та журналює отриману сцену: після стискання журналу чи перезапуску історії,
+// на яку посилаються undo та redo, вже немає.
+func (p *Parser) restore(step func() error) ([]painter.Operation, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.uistate.ResetOperations()
+	p.recordInitialState()
+	if err := step(); err != nil {
+		return nil, err
+	}
+	scene := p.uistate.Scene()
+	p.accept(JournalEntry{Scene: &scene})
+	p.uistate.SetUpdateOperation()
+	return p.operations(), nil
+}
+
+func (p *Parser) undo() error {
+	scene, err := p.history.undo()
+	if err != nil {
+		return err
+	}
+	return p.uistate.SetScene(scene)
+}
+
+func (p *Parser) redo() error {
+	scene, err := p.history.redo()
+	if err != nil {
+		return err
+	}
+	return p.uistate.SetScene(scene)
+}
+
+// Version повертає версію сцени - кількість прийнятих змін від запуску.
+func (p *Parser) Version() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.version
+}
+
+// accept збільшує версію сцени, повідомляє про прийняту зміну entry, додає її до журналу змін та записує в журнал,
+// за потреби стискаючи його до знімка поточної сцени. Помилки журналу лише логуються: зміна вже застосована до сцени.
+func (p *Parser) accept(entry JournalEntry) {
+	p.version++
+	entry.Seq, entry.Time = p.version, time.Now()
+	if p.Events != nil {
+		p.Events.Publish(Event{Type: "accepted", Time: entry.Time, Version: p.version})
+	}
+	if p.Changes != nil {
+		p.Changes.append(entry)
+	}
+	if p.replaying {
+		return
+	}
+	if p.Session != nil {
+		if err := p.Session.record(entry); err != nil {
+			log.Printf("Failed to record session: %s", err)
 		}
 	}
+	if p.Journal == nil {
+		return
+	}
+	var err error
+	if entry.Scene != nil {
+		err = p.Journal.AppendScene(*entry.Scene)
+	} else {
+		err = p.Journal.Append(entry.Script)
+	}
+	if err == nil && p.Journal.NeedsCompaction() {
+		err = p.Journal.Compact(p.uistate.Scene())
+	}
+	if err != nil {
+		log.Printf("Journal error: %s", err)
+	}
+}
+
+// startReplay переводить парсер у режим відтворення та повертає функцію, що завершує його.
+func (p *Parser) startReplay() func() {
+	p.mu.Lock()
+	p.replaying = true
+	p.mu.Unlock()
+	return func() {
+		p.mu.Lock()
+		p.replaying = false
+		p.mu.Unlock()
+	}
+}
 
-	res := p.uistate.GetOperations()
+// reject повідомляє про відхилений скрипт і повертає причину.
+func (p *Parser) reject(err error) error {
+	if p.Events != nil {
+		p.Events.Publish(Event{Type: "rejected", Time: time.Now(), Version: p.version, Error: err.Error()})
+	}
+	return err
+}
 
-	return res, nil
+// recordInitialState записує в історію стан сцени до першої зміни.
+func (p *Parser) recordInitialState() {
+	if len(p.history.states) == 0 {
+		p.history.record(p.uistate.Scene())
+	}
 }
 
 func (p *Parser) parse(cmdl string) error {
-	words := strings.Split(cmdl, " ")
+	words := strings.Fields(cmdl)
 	command := words[0]
 
 	switch command {
@@ -51,6 +330,21 @@ func (p *Parser) parse(cmdl string) error {
 			return fmt.Errorf("wrong number of arguments for green command")
 		}
 		p.uistate.GreenBackground()
+	case "fill":
+		paint, err := parsePaint(command, words[1:])
+		if err != nil {
+			return err
+		}
+		p.uistate.PaintBackground(paint)
+	case "paint":
+		if len(words) < 3 {
+			return fmt.Errorf("wrong number of arguments for '%v' command", command)
+		}
+		paint, err := parsePaint(command, words[2:])
+		if err != nil {
+			return err
+		}
+		return p.uistate.SetPaint(words[1], paint)
 	case "bgrect":
 		parameters, err := checkForErrorsInParameters(words, 5)
 		if err != nil {
@@ -58,18 +352,196 @@ func (p *Parser) parse(cmdl string) error {
 		}
 		p.uistate.BackgroundRectangle(image.Point{X: parameters[0], Y: parameters[1]}, image.Point{X: parameters[2], Y: parameters[3]})
 	case "figure":
+		if len(words) != 3 && len(words) != 5 && len(words) != 6 && len(words) != 7 {
+			return fmt.Errorf("wrong number of arguments for '%v' command", command)
+		}
+		parameters, err := checkForErrorsInParameters(words[:3], 3)
+		if err != nil {
+			return err
+		}
+		figure := &painter.CrossFigure{CentralPoint: image.Point{X: parameters[0], Y: parameters[1]}}
+		if len(words) > 3 {
+			size, err := checkForErrorsInParameters(append(words[:1:1], words[3:5]...), 3)
+			if err != nil {
+				return err
+			}
+			if size[0] <= 0 || size[1] <= 0 {
+				return fmt.Errorf("arm length and width for '%v' command must be positive", command)
+			}
+			figure.ArmLength, figure.ArmWidth = size[0], size[1]
+		}
+		if len(words) > 5 {
+			if figure.Angle, err = parseFloatParameter(command, words[5]); err != nil {
+				return err
+			}
+		}
+		if len(words) > 6 {
+			if figure.Scale, err = parseFloatParameter(command, words[6]); err != nil {
+				return err
+			}
+			if figure.Scale <= 0 {
+				return fmt.Errorf("scale for '%v' command must be positive", command)
+			}
+		}
+		p.uistate.AddFigure(figure)
+	case "push":
+		if len(words) != 1 {
+			return fmt.Errorf("wrong number of arguments for push command")
+		}
+		p.uistate.PushTransform()
+	case "pop":
+		if len(words) != 1 {
+			return fmt.Errorf("wrong number of arguments for pop command")
+		}
+		return p.uistate.PopTransform()
+	case "translate":
 		parameters, err := checkForErrorsInParameters(words, 3)
 		if err != nil {
 			return err
 		}
-
-		p.uistate.AddFigure(image.Point{X: parameters[0], Y: parameters[1]})
+		p.uistate.Translate(parameters[0], parameters[1])
+	case "rotate":
+		if len(words) == 2 {
+			angle, err := parseFloatParameter(command, words[1])
+			if err != nil {
+				return err
+			}
+			p.uistate.Rotate(angle)
+			return nil
+		}
+		id, angle, err := parseFigureParameters(words)
+		if err != nil {
+			return err
+		}
+		return p.uistate.AddRotateOperation(id, angle)
+	case "scale":
+		if len(words) == 2 {
+			factor, err := parseFloatParameter(command, words[1])
+			if err != nil {
+				return err
+			}
+			if factor <= 0 {
+				return fmt.Errorf("scale factor must be positive")
+			}
+			p.uistate.Scale(factor)
+			return nil
+		}
+		id, factor, err := parseFigureParameters(words)
+		if err != nil {
+			return err
+		}
+		if factor <= 0 {
+			return fmt.Errorf("scale factor must be positive")
+		}
+		return p.uistate.AddScaleOperation(id, factor)
 	case "move":
+		if len(words) == 4 {
+			parameters, err := checkForErrorsInParameters(append(words[:1:1], words[2:]...), 3)
+			if err != nil {
+				return err
+			}
+			return p.uistate.AddGroupMoveOperation(words[1], parameters[0], parameters[1])
+		}
 		parameters, err := checkForErrorsInParameters(words, 3)
 		if err != nil {
 			return err
 		}
 		p.uistate.AddMoveOperation(parameters[0], parameters[1])
+	case "group":
+		if len(words) != 3 || words[2] != "{" {
+			return fmt.Errorf("group declaration must look like 'group <name> {'")
+		}
+		return p.uistate.OpenGroup(words[1])
+	case "}":
+		if len(words) != 1 {
+			return fmt.Errorf("unexpected arguments after closing brace")
+		}
+		return p.uistate.CloseGroup()
+	case "hide", "show":
+		if len(words) != 2 {
+			return fmt.Errorf("wrong number of arguments for '%v' command", command)
+		}
+		return p.uistate.SetGroupHidden(words[1], command == "hide")
+	case "color":
+		if len(words) != 3 {
+			return fmt.Errorf("wrong number of arguments for '%v' command", command)
+		}
+		c, err := parseColor(words[2])
+		if err != nil {
+			return err
+		}
+		return p.uistate.SetGroupColor(words[1], c)
+	case "delete":
+		if len(words) != 2 {
+			return fmt.Errorf("wrong number of arguments for '%v' command", command)
+		}
+		return p.uistate.DeleteGroup(words[1])
+	case "clip":
+		if len(words) == 2 {
+			return p.uistate.SetClipToShape(words[1])
+		}
+		parameters, err := checkForErrorsInParameters(words, 5)
+		if err != nil {
+			return err
+		}
+		p.uistate.SetClip(painter.ClipRect(image.Rect(parameters[0], parameters[1], parameters[2], parameters[3])))
+	case "unclip":
+		if len(words) != 1 {
+			return fmt.Errorf("wrong number of arguments for unclip command")
+		}
+		p.uistate.SetClip(nil)
+	case "save", "load":
+		if len(words) != 2 {
+			return fmt.Errorf("wrong number of arguments for '%v' command", command)
+		}
+		// Відтворений скрипт не перезаписує файлів, тож шлях save не перевіряється: парсер відтворення може не мати Dir.
+		if command == "save" && p.replaying {
+			return nil
+		}
+		path, err := p.scenePath(words[1])
+		if err != nil {
+			return err
+		}
+		if command == "save" {
+			p.saves = append(p.saves, pendingSave{path: path, scene: p.uistate.Scene()})
+			return nil
+		}
+		if group := p.uistate.openGroup(); group != nil {
+			return fmt.Errorf("cannot load a scene inside the declaration of group %s", group.Name)
+		}
+		p.restored = true
+		return p.uistate.LoadScene(path)
+	case "record":
+		if len(words) < 2 || words[1] == "start" && len(words) != 3 || words[1] == "stop" && len(words) != 2 {
+			return fmt.Errorf("record command must look like 'record start <file>' or 'record stop'")
+		}
+		if words[1] != "start" && words[1] != "stop" {
+			return fmt.Errorf("record command must look like 'record start <file>' or 'record stop'")
+		}
+		if p.replaying {
+			return nil
+		}
+		if p.Recorder == nil {
+			return fmt.Errorf("recording is not enabled")
+		}
+		if words[1] == "start" {
+			path, err := p.scenePath(words[2])
+			if err != nil {
+				return err
+			}
+			p.uistate.StartRecording(p.Recorder, path)
+		} else {
+			p.uistate.StopRecording(p.Recorder)
+		}
+	case "undo", "redo":
+		if len(words) != 1 {
+			return fmt.Errorf("wrong number of arguments for %v command", command)
+		}
+		p.restored = true
+		if command == "undo" {
+			return p.undo()
+		}
+		return p.redo()
 	case "reset":
 		if len(words) != 1 {
 			return fmt.Errorf("wrong number of arguments for reset command")
@@ -86,6 +558,38 @@ func (p *Parser) parse(cmdl string) error {
 	return nil
 }
 
+// scenePath повертає шлях до файлу сцени чи анімації name у каталозі Dir. Абсолютні шляхи та шляхи з ".." заборонені, щоб
+// клієнти не могли читати й записувати файли поза цим каталогом.
+func (p *Parser) scenePath(name string) (string, error) {
+	if p.Dir == "" {
+		return "", fmt.Errorf("scene files are not enabled")
+	}
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("scene file %s must be a relative path inside the scene directory", name)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(name), "/") {
+		if part == ".." {
+			return "", fmt.Errorf("scene file %s must be a relative path inside the scene directory", name)
+		}
+	}
+	return filepath.Join(p.Dir, name), nil
+}
+
+// GroupDepth повертає, на скільки рядок скрипту змінює вкладеність оголошень груп: 1 для "group <name> {",
+// -1 для "}" та 0 для інших команд. Дозволяє надсилати оголошення групи парсеру одним скриптом.
+func GroupDepth(line string) int {
+	words := strings.Fields(line)
+	switch {
+	case len(words) == 0:
+		return 0
+	case words[0] == "group" && words[len(words)-1] == "{":
+		return 1
+	case words[0] == "}":
+		return -1
+	}
+	return 0
+}
+
 func checkForErrorsInParameters(words []string, expected int) ([]int, error) {
 	if len(words) != expected {
 		return nil, fmt.Errorf("wrong number of arguments for '%v' command", words[0])
@@ -102,10 +606,34 @@ func checkForErrorsInParameters(words []string, expected int) ([]int, error) {
 	return params, nil
 }
 
+// parseFigureParameters розбирає аргументи команд виду "<command> <id> <value>".
+func parseFigureParameters(words []string) (int, float64, error) {
+	if len(words) != 3 {
+		return 0, 0, fmt.Errorf("wrong number of arguments for '%v' command", words[0])
+	}
+	id, err := strconv.Atoi(words[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid figure id for '%s' command: '%s'", words[0], words[1])
+	}
+	value, err := parseFloatParameter(words[0], words[2])
+	if err != nil {
+		return 0, 0, err
+	}
+	return id, value, nil
+}
+
+func parseFloatParameter(command, param string) (float64, error) {
+	f, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid parameter for '%s' command: '%s' is not a number", command, param)
+	}
+	return f, nil
+}
+
 func parseInt(s string) (int, error) {
 	f, err := strconv.ParseFloat(s, 64)
 	if err != nil {
 		return 0, fmt.Errorf("cannot parse float: %s", s)
 	}
-	return int(f * 800), nil
+	return int(f * painter.CanvasSize), nil
 }
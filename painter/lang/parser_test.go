@@ -1,7 +1,12 @@
 package lang
 
 import (
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
 	"image"
+	"image/color"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -31,6 +36,114 @@ func Test_parse_struct(t *testing.T) {
 			command: "figure 0.25 0.25",
 			op:      &painter.CrossFigure{CentralPoint: image.Point{X: 200, Y: 200}},
 		},
+		{
+			name:    "figure with geometry",
+			command: "figure 0.25 0.25 0.25 0.125 45 2",
+			op: &painter.CrossFigure{
+				CentralPoint: image.Point{X: 200, Y: 200},
+				ArmLength:    200,
+				ArmWidth:     100,
+				Angle:        45,
+				Scale:        2,
+			},
+		},
+		{
+			name:    "rotate",
+			command: "figure 0.5 0.5\nrotate 1 30",
+			op:      &painter.RotateOperation{Angle: 30, Figure: &painter.CrossFigure{CentralPoint: image.Point{X: 400, Y: 400}}},
+		},
+		{
+			name:    "scale",
+			command: "figure 0.5 0.5\nscale 1 1.5",
+			op:      &painter.ScaleOperation{Factor: 1.5, Figure: &painter.CrossFigure{CentralPoint: image.Point{X: 400, Y: 400}}},
+		},
+		{
+			name:    "translated figure",
+			command: "translate 0.25 0.25\nfigure 0.25 0.25",
+			op:      &painter.CrossFigure{CentralPoint: image.Point{X: 400, Y: 400}, Scale: 1},
+		},
+		{
+			name:    "rotated and scaled figure",
+			command: "translate 0.5 0.5\nrotate 90\nscale 0.5\nfigure 0.25 0",
+			op:      &painter.CrossFigure{CentralPoint: image.Point{X: 400, Y: 500}, Angle: 90, Scale: 0.5},
+		},
+		{
+			name:    "pushed and popped transform",
+			command: "push\ntranslate 0.25 0.25\npop\nfigure 0.25 0.25",
+			op:      &painter.CrossFigure{CentralPoint: image.Point{X: 200, Y: 200}},
+		},
+		{
+			name:    "scaled background rectangle",
+			command: "scale 2\nbgrect 0 0 0.125 0.125",
+			op:      &painter.BackgroundRectangle{FirstPoint: image.Point{X: 0, Y: 0}, SecondPoint: image.Point{X: 200, Y: 200}},
+		},
+		{
+			name:    "group",
+			command: "group g {\n  figure 0.5 0.5\n  group inner {\n    bgrect 0 0 0.125 0.125\n  }\n}\ncolor inner #ff0000",
+			op: &painter.Group{Name: "g", Shapes: []painter.Shape{
+				&painter.CrossFigure{CentralPoint: image.Point{X: 400, Y: 400}},
+				&painter.Group{Name: "inner", Shapes: []painter.Shape{
+					&painter.BackgroundRectangle{
+						FirstPoint:  image.Point{X: 0, Y: 0},
+						SecondPoint: image.Point{X: 100, Y: 100},
+						Color:       color.NRGBA{R: 0xff, A: 0xff},
+					},
+				}},
+			}},
+		},
+		{
+			name:    "hidden group",
+			command: "group g {\nfigure 0.5 0.5\n}\nhide g",
+			op:      &painter.Group{Name: "g", Shapes: []painter.Shape{&painter.CrossFigure{CentralPoint: image.Point{X: 400, Y: 400}}}, Hidden: true},
+		},
+		{
+			name:    "group move",
+			command: "group g {\n}\nmove g 0.125 0.125",
+			op:      &painter.GroupMoveOperation{X: 100, Y: 100, Group: &painter.Group{Name: "g"}},
+		},
+		{
+			name:    "painted figure",
+			command: "figure 0.5 0.5\npaint 1 checker 0.05 white #000000",
+			op: &painter.CrossFigure{
+				CentralPoint: image.Point{X: 400, Y: 400},
+				Paint:        painter.Checkerboard{Size: 40, A: color.White, B: color.NRGBA{A: 0xff}},
+			},
+		},
+		{
+			name:    "painted background rectangle",
+			command: "bgrect 0 0 0.125 0.125\npaint bgrect linear 0 0 0.125 0 red blue",
+			op: &painter.BackgroundRectangle{
+				FirstPoint:  image.Point{X: 0, Y: 0},
+				SecondPoint: image.Point{X: 100, Y: 100},
+				Paint: painter.LinearGradient{
+					To:        image.Point{X: 100},
+					FromColor: color.RGBA{R: 0xff, A: 0xff},
+					ToColor:   color.RGBA{B: 0xff, A: 0xff},
+				},
+			},
+		},
+		{
+			name:    "clipped figure",
+			command: "clip 0 0 0.5 0.5\nfigure 0.5 0.5",
+			op: &painter.CrossFigure{
+				CentralPoint: image.Point{X: 400, Y: 400},
+				Clip:         painter.ClipRect(image.Rect(0, 0, 400, 400)),
+			},
+		},
+		{
+			name:    "unclipped figure",
+			command: "clip 0 0 0.5 0.5\nunclip\nfigure 0.5 0.5",
+			op:      &painter.CrossFigure{CentralPoint: image.Point{X: 400, Y: 400}},
+		},
+		{
+			name:    "rectangle clipped to figure",
+			command: "figure 0.5 0.5\nclip 1\nbgrect 0 0 0.125 0.125",
+			op: &painter.BackgroundRectangle{
+				FirstPoint:  image.Point{X: 0, Y: 0},
+				SecondPoint: image.Point{X: 100, Y: 100},
+				Clip:        &painter.CrossFigure{CentralPoint: image.Point{X: 400, Y: 400}},
+			},
+		},
 		{
 			name:    "move",
 			command: "move 0.125 0.125",
@@ -76,6 +189,91 @@ func Test_parse_struct(t *testing.T) {
 			command: "figure ah",
 			op:      nil,
 		},
+		{
+			name:    "wrong geometry args figure",
+			command: "figure 0.125 0.125 0.5 0",
+			op:      nil,
+		},
+		{
+			name:    "wrong scale args figure",
+			command: "figure 0.125 0.125 0.5 0.25 0 -1",
+			op:      nil,
+		},
+		{
+			name:    "rotate missing figure",
+			command: "rotate 1 30",
+			op:      nil,
+		},
+		{
+			name:    "wrong args scale",
+			command: "figure 0.5 0.5\nscale 1 0",
+			op:      nil,
+		},
+		{
+			name:    "pop without push",
+			command: "pop",
+			op:      nil,
+		},
+		{
+			name:    "wrong args translate",
+			command: "translate 0.5",
+			op:      nil,
+		},
+		{
+			name:    "unclosed group",
+			command: "group g {\nfigure 0.5 0.5",
+			op:      nil,
+		},
+		{
+			name:    "duplicate group",
+			command: "group g {\n}\ngroup g {\n}",
+			op:      nil,
+		},
+		{
+			name:    "numeric group name",
+			command: "group 1 {\n}",
+			op:      nil,
+		},
+		{
+			name:    "reserved group name",
+			command: "group bgrect {\n}",
+			op:      nil,
+		},
+		{
+			name:    "unknown group",
+			command: "hide g",
+			op:      nil,
+		},
+		{
+			name:    "wrong color",
+			command: "group g {\n}\ncolor g purple",
+			op:      nil,
+		},
+		{
+			name:    "unknown fill",
+			command: "fill sparkles white black",
+			op:      nil,
+		},
+		{
+			name:    "wrong args fill",
+			command: "fill radial 0.5 0.5 white black",
+			op:      nil,
+		},
+		{
+			name:    "paint missing rectangle",
+			command: "paint bgrect white",
+			op:      nil,
+		},
+		{
+			name:    "wrong args clip",
+			command: "clip 0 0 0.5",
+			op:      nil,
+		},
+		{
+			name:    "clip to unknown shape",
+			command: "clip 1",
+			op:      nil,
+		},
 		{
 			name:    "not enough args move",
 			command: "move 0.125",
@@ -91,6 +289,16 @@ func Test_parse_struct(t *testing.T) {
 			command: "move gs j",
 			op:      nil,
 		},
+		{
+			name:    "record without recorder",
+			command: "record start out.gif",
+			op:      nil,
+		},
+		{
+			name:    "wrong args record",
+			command: "record start",
+			op:      nil,
+		},
 	}
 	// Looping through the test cases.
 	for _, tc := range tests {
@@ -133,6 +341,16 @@ func Test_parse_func(t *testing.T) {
 			command: "green",
 			op:      painter.OperationFunc(painter.GreenFill), // The expected operation is a function call to GreenFill
 		},
+		{
+			name:    "gradient fill",
+			command: "fill radial 0.5 0.5 0.5 white green",
+			op:      &painter.PaintFill{}, // The expected operation fills the texture with a gradient
+		},
+		{
+			name:    "clipped white fill",
+			command: "clip 0 0 0.5 0.5\nwhite",
+			op:      &painter.ClipOperation{}, // The expected operation fills only the clipping region
+		},
 		{
 			name:    "reset screen",
 			command: "reset",
@@ -162,3 +380,81 @@ func Test_parse_func(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_ErrorLine(t *testing.T) {
+	_, err := (&Parser{}).Parse(strings.NewReader("white\n\nfigure 0.5\nupdate"))
+	assert.EqualError(t, err, "line 3: wrong number of arguments for 'figure' command")
+}
+
+func TestCommands(t *testing.T) {
+	for _, command := range Commands {
+		_, err := (&Parser{}).Parse(strings.NewReader(command))
+		if err != nil {
+			assert.NotContains(t, err.Error(), "invalid command", command)
+		}
+	}
+
+	// Every command the parser handles is listed, so a new command cannot be left out of Commands.
+	file, err := goparser.ParseFile(token.NewFileSet(), "parser.go", nil, 0)
+	require.NoError(t, err)
+	var handled []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		if fn, ok := n.(*ast.FuncDecl); ok && fn.Recv != nil && fn.Name.Name == "parse" {
+			for _, stmt := range fn.Body.List {
+				if sw, ok := stmt.(*ast.SwitchStmt); ok {
+					for _, clause := range sw.Body.List {
+						for _, expr := range clause.(*ast.CaseClause).List {
+							if name, err := strconv.Unquote(expr.(*ast.BasicLit).Value); err == nil && name != "}" {
+								handled = append(handled, name)
+							}
+						}
+					}
+				}
+			}
+		}
+		return true
+	})
+	assert.ElementsMatch(t, Commands, handled)
+}
+
+func TestGroupDepth(t *testing.T) {
+	assert.Equal(t, 1, GroupDepth("group g {"))
+	assert.Equal(t, -1, GroupDepth(" }"))
+	assert.Equal(t, 0, GroupDepth("figure 0.5 0.5"))
+	assert.Equal(t, 0, GroupDepth(""))
+}
+
+func TestParser_OperationsDrawCopies(t *testing.T) {
+	parser := &Parser{}
+	ops, err := parser.Parse(strings.NewReader("figure 0.5 0.5\nmove 0.125 0\nrotate 1 45"))
+	require.NoError(t, err)
+
+	// The scene already reflects the moves before the loop runs the operations.
+	figure := parser.Scene().Figures[0]
+	assert.Equal(t, &ScenePoint{X: 500, Y: 400}, figure.Center)
+	assert.Equal(t, 45.0, figure.Angle)
+
+	// Running the operations moves only the copies they draw.
+	texture := painter.NewImageTexture(image.Pt(800, 800))
+	for _, op := range ops {
+		op.Do(texture)
+	}
+	require.IsType(t, &painter.CrossFigure{}, ops[len(ops)-1])
+	assert.Equal(t, image.Pt(500, 400), ops[len(ops)-1].(*painter.CrossFigure).CentralPoint)
+	assert.Equal(t, figure, parser.Scene().Figures[0])
+}
+
+func TestParser_TransformAndClipPerScript(t *testing.T) {
+	parser := &Parser{}
+	_, err := parser.Parse(strings.NewReader("push\ntranslate 0.25 0.25\nclip 0 0 0.5 0.5"))
+	require.NoError(t, err)
+
+	// Another client's script starts without the previous transform and clip.
+	_, err = parser.Parse(strings.NewReader("figure 0.25 0.25\npop"))
+	assert.EqualError(t, err, "line 2: pop without matching push")
+	_, err = parser.Parse(strings.NewReader("figure 0.25 0.25"))
+	require.NoError(t, err)
+	figure := parser.Scene().Figures[0]
+	assert.Equal(t, &ScenePoint{X: 200, Y: 200}, figure.Center)
+	assert.Nil(t, figure.Clip)
+}
@@ -0,0 +1,151 @@
+package lang
+
+import (
+	"encoding/json"
+	"image"
+	"net/http"
+	"strconv"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+)
+
+// Hit фігура, що містить точку запиту HitTest.
+type Hit struct {
+	Type  string `json:"type"`            // rectangle або figure
+	ID    string `json:"id,omitempty"`    // bgrect або номер фігури; порожній для фігур у групах
+	Group string `json:"group,omitempty"` // назва групи, до якої входить фігура
+}
+
+// Box межі фігури в одиницях команд.
+type Box struct {
+	From StatePoint `json:"from"`
+	To   StatePoint `json:"to"`
+}
+
+// HitTest повертає фігури, що зафарбовують точку (x, y), задану в одиницях команд, починаючи з верхньої.
+// Приховані групи та частини фігур за межами їх областей відсікання не враховуються, а відкладені зміни - так,
+// як їх показує State.
+func (p *Parser) HitTest(x, y float64) ([]Hit, error) {
+	u, err := p.projected()
+	if err != nil {
+		return nil, err
+	}
+	return u.HitTest(image.Pt(int(x*painter.CanvasSize), int(y*painter.CanvasSize))), nil
+}
+
+// Bounds повертає межі фігури target (bgrect, номер фігури або назва групи) в одиницях команд з урахуванням
+// відкладених змін.
+func (p *Parser) Bounds(target string) (Box, error) {
+	u, err := p.projected()
+	if err != nil {
+		return Box{}, err
+	}
+	shape, err := u.shape(target)
+	if err != nil {
+		return Box{}, err
+	}
+	b := shape.Bounds()
+	return Box{
+		From: StatePoint{X: units(b.Min.X), Y: units(b.Min.Y)},
+		To:   StatePoint{X: units(b.Max.X), Y: units(b.Max.Y)},
+	}, nil
+}
+
+// projected повертає окремий стан, побудований з документа Scene, тобто з уже застосованими відкладеними змінами.
+func (p *Parser) projected() (*Uistate, error) {
+	scene := p.Scene()
+	var u Uistate
+	if err := u.SetScene(scene); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// HitTest повертає фігури, що зафарбовують піксель pt, у порядку, зворотному до порядку малювання.
+func (u *Uistate) HitTest(pt image.Point) []Hit {
+	var hits []Hit
+	visible := func(clip painter.Region) bool {
+		return clip == nil || clip.Contains(pt)
+	}
+	var visitGroup func(group *painter.Group)
+	visitGroup = func(group *painter.Group) {
+		if group.Hidden || !visible(group.Clip) {
+			return
+		}
+		for _, shape := range group.Shapes {
+			switch shape := shape.(type) {
+			case *painter.BackgroundRectangle:
+				if visible(shape.Clip) && shape.Contains(pt) {
+					hits = append(hits, Hit{Type: "rectangle", Group: group.Name})
+				}
+			case *painter.CrossFigure:
+				if visible(shape.Clip) && shape.Contains(pt) {
+					hits = append(hits, Hit{Type: "figure", Group: group.Name})
+				}
+			case *painter.Group:
+				visitGroup(shape)
+			}
+		}
+	}
+
+	if rect := u.backgroundRectangle; rect != nil && visible(rect.Clip) && rect.Contains(pt) {
+		hits = append(hits, Hit{Type: "rectangle", ID: "bgrect"})
+	}
+	for i, figure := range u.figuresArray {
+		if visible(figure.Clip) && figure.Contains(pt) {
+			hits = append(hits, Hit{Type: "figure", ID: strconv.Itoa(i + 1)})
+		}
+	}
+	visitGroup(&u.groups)
+
+	for i, j := 0, len(hits)-1; i < j; i, j = i+1, j-1 {
+		hits[i], hits[j] = hits[j], hits[i]
+	}
+	return hits
+}
+
+// HitHandler конструює обробник GET запитів /hit?x=X&y=Y, який повертає JSON список фігур у точці (X, Y),
+// заданій в одиницях команд, починаючи з верхньої.
+func HitHandler(p *Parser) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		query := r.URL.Query()
+		x, errX := strconv.ParseFloat(query.Get("x"), 64)
+		y, errY := strconv.ParseFloat(query.Get("y"), 64)
+		if errX != nil || errY != nil {
+			http.Error(rw, "x and y must be numbers", http.StatusBadRequest)
+			return
+		}
+		hits, err := p.HitTest(x, y)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if hits == nil {
+			hits = []Hit{}
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(hits)
+	})
+}
+
+// BoundsHandler конструює обробник GET запитів /bounds?target=T, який повертає межі фігури T (bgrect, номер
+// фігури або назва групи) в одиницях команд.
+func BoundsHandler(p *Parser) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		box, err := p.Bounds(r.URL.Query().Get("target"))
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(box)
+	})
+}
@@ -0,0 +1,97 @@
+package lang
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_HitTest(t *testing.T) {
+	p := &Parser{}
+	_, err := p.Parse(strings.NewReader(strings.Join([]string{
+		"white",
+		"bgrect 0 0 0.5 0.5",
+		"figure 0.25 0.25",
+		"group g {",
+		"figure 0.3 0.3",
+		"}",
+		"group h {",
+		"figure 0.75 0.75",
+		"}",
+		"hide h",
+	}, "\n")))
+	require.NoError(t, err)
+
+	hitTest := func(x, y float64) []Hit {
+		hits, err := p.HitTest(x, y)
+		require.NoError(t, err)
+		return hits
+	}
+	assert.Equal(t, []Hit{
+		{Type: "figure", Group: "g"},
+		{Type: "figure", ID: "1"},
+		{Type: "rectangle", ID: "bgrect"},
+	}, hitTest(0.25, 0.25))
+	assert.Equal(t, []Hit{{Type: "rectangle", ID: "bgrect"}}, hitTest(0.01, 0.49))
+	assert.Empty(t, hitTest(0.75, 0.75), "hidden groups are not hit")
+}
+
+func TestParser_Bounds(t *testing.T) {
+	p := &Parser{}
+	_, err := p.Parse(strings.NewReader("bgrect 0.25 0.25 0.75 0.5\nfigure 0.5 0.5"))
+	require.NoError(t, err)
+
+	box, err := p.Bounds("bgrect")
+	require.NoError(t, err)
+	assert.Equal(t, Box{From: StatePoint{X: 0.25, Y: 0.25}, To: StatePoint{X: 0.75, Y: 0.5}}, box)
+
+	// The default cross has 400px arms.
+	box, err = p.Bounds("1")
+	require.NoError(t, err)
+	assert.Equal(t, Box{From: StatePoint{X: 0.25, Y: 0.25}, To: StatePoint{X: 0.75, Y: 0.75}}, box)
+
+	_, err = p.Bounds("2")
+	assert.Error(t, err)
+}
+
+func TestParser_QueriesSeePendingMoves(t *testing.T) {
+	p := &Parser{}
+	_, err := p.Parse(strings.NewReader("figure 0.5 0.5"))
+	require.NoError(t, err)
+
+	// A move that has not been turned into operations yet is pending, and the queries agree with State about it.
+	p.uistate.AddMoveOperation(100, 0)
+	box, err := p.Bounds("1")
+	require.NoError(t, err)
+	assert.Equal(t, Box{From: StatePoint{X: 0.375, Y: 0.25}, To: StatePoint{X: 0.875, Y: 0.75}}, box)
+	assert.Equal(t, (box.From.X+box.To.X)/2, p.State().Figures[0].X)
+
+	hits, err := p.HitTest(0.85, 0.5)
+	require.NoError(t, err)
+	assert.Equal(t, []Hit{{Type: "figure", ID: "1"}}, hits)
+}
+
+func TestHitHandler(t *testing.T) {
+	p := &Parser{}
+	_, err := p.Parse(strings.NewReader("figure 0.5 0.5"))
+	require.NoError(t, err)
+	server := httptest.NewServer(HitHandler(p))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?x=0.5&y=0.5")
+	require.NoError(t, err)
+	var hits []Hit
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&hits))
+	resp.Body.Close()
+	assert.Equal(t, []Hit{{Type: "figure", ID: "1"}}, hits)
+
+	resp, err = http.Get(server.URL + "?x=left")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,590 @@
+package lang
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+)
+
+// SceneVersion версія формату документа сцени, яку створює та розуміє парсер.
+const SceneVersion = 1
+
+// Scene документ зі станом сцени. Усі координати задаються у пікселях текстури.
+type Scene struct {
+	Version        int          `json:"version"`
+	Background     *ScenePaint  `json:"background,omitempty"`
+	BackgroundClip *SceneRegion `json:"backgroundClip,omitempty"`
+	Rectangle      *SceneShape  `json:"rectangle,omitempty"`
+	Figures        []SceneShape `json:"figures,omitempty"`
+	Groups         []SceneShape `json:"groups,omitempty"`
+}
+
+// SceneShape опис прямокутника ("rectangle"), хреста ("figure") або групи ("group").
+type SceneShape struct {
+	Type      string       `json:"type"`
+	ID        int          `json:"id,omitempty"`
+	Name      string       `json:"name,omitempty"`
+	From      *ScenePoint  `json:"from,omitempty"`
+	To        *ScenePoint  `json:"to,omitempty"`
+	Center    *ScenePoint  `json:"center,omitempty"`
+	ArmLength int          `json:"armLength,omitempty"`
+	ArmWidth  int          `json:"armWidth,omitempty"`
+	Angle     float64      `json:"angle,omitempty"`
+	Scale     float64      `json:"scale,omitempty"`
+	Color     string       `json:"color,omitempty"`
+	Paint     *ScenePaint  `json:"paint,omitempty"`
+	Clip      *SceneRegion `json:"clip,omitempty"`
+	Hidden    bool         `json:"hidden,omitempty"`
+	Shapes    []SceneShape `json:"shapes,omitempty"`
+}
+
+// ScenePaint опис заливки: solid, linear, radial, checker, stripes або hatch.
+type ScenePaint struct {
+	Type      string      `json:"type"`
+	Colors    []string    `json:"colors"`
+	From      *ScenePoint `json:"from,omitempty"`
+	To        *ScenePoint `json:"to,omitempty"`
+	Center    *ScenePoint `json:"center,omitempty"`
+	Origin    *ScenePoint `json:"origin,omitempty"`
+	Radius    int         `json:"radius,omitempty"`
+	Size      int         `json:"size,omitempty"`
+	Width     int         `json:"width,omitempty"`
+	Spacing   int         `json:"spacing,omitempty"`
+	LineWidth int         `json:"lineWidth,omitempty"`
+	Angle     float64     `json:"angle,omitempty"`
+}
+
+// SceneRegion область відсікання: прямокутник From-To або фігура сцени Target ("bgrect", номер фігури чи назва групи).
+type SceneRegion struct {
+	From   *ScenePoint `json:"from,omitempty"`
+	To     *ScenePoint `json:"to,omitempty"`
+	Target string      `json:"target,omitempty"`
+}
+
+type ScenePoint struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Scaled повертає копію сцени, у якій усі координати та розміри помножено на k. Кути, кольори та масштаб хрестів
+// не змінюються.
+func (s Scene) Scaled(k float64) Scene {
+	scaled := s
+	scaled.Background = s.Background.scaled(k)
+	scaled.BackgroundClip = s.BackgroundClip.scaled(k)
+	if s.Rectangle != nil {
+		rect := s.Rectangle.scaled(k)
+		scaled.Rectangle = &rect
+	}
+	scaled.Figures = scaleShapes(s.Figures, k)
+	scaled.Groups = scaleShapes(s.Groups, k)
+	return scaled
+}
+
+func scaleShapes(shapes []SceneShape, k float64) []SceneShape {
+	if shapes == nil {
+		return nil
+	}
+	scaled := make([]SceneShape, len(shapes))
+	for i, shape := range shapes {
+		scaled[i] = shape.scaled(k)
+	}
+	return scaled
+}
+
+func (s SceneShape) scaled(k float64) SceneShape {
+	s.From, s.To, s.Center = s.From.scaled(k), s.To.scaled(k), s.Center.scaled(k)
+	if s.Type == "figure" {
+		// Нульові розміри означають типову геометрію, яку теж потрібно масштабувати.
+		if s.ArmLength == 0 {
+			s.ArmLength = painter.DefaultArmLength
+		}
+		if s.ArmWidth == 0 {
+			s.ArmWidth = painter.DefaultArmWidth
+		}
+		s.ArmLength, s.ArmWidth = scaleLength(s.ArmLength, k), scaleLength(s.ArmWidth, k)
+	}
+	s.Paint = s.Paint.scaled(k)
+	s.Clip = s.Clip.scaled(k)
+	s.Shapes = scaleShapes(s.Shapes, k)
+	return s
+}
+
+func (p *ScenePaint) scaled(k float64) *ScenePaint {
+	if p == nil {
+		return nil
+	}
+	scaled := *p
+	scaled.From, scaled.To = p.From.scaled(k), p.To.scaled(k)
+	scaled.Center, scaled.Origin = p.Center.scaled(k), p.Origin.scaled(k)
+	scaled.Radius, scaled.Size = scaleLength(p.Radius, k), scaleLength(p.Size, k)
+	scaled.Width, scaled.Spacing = scaleLength(p.Width, k), scaleLength(p.Spacing, k)
+	scaled.LineWidth = scaleLength(p.LineWidth, k)
+	return &scaled
+}
+
+func (r *SceneRegion) scaled(k float64) *SceneRegion {
+	if r == nil {
+		return nil
+	}
+	return &SceneRegion{From: r.From.scaled(k), To: r.To.scaled(k), Target: r.Target}
+}
+
+func (p *ScenePoint) scaled(k float64) *ScenePoint {
+	if p == nil {
+		return nil
+	}
+	return &ScenePoint{X: scaleLength(p.X, k), Y: scaleLength(p.Y, k)}
+}
+
+func scaleLength(v int, k float64) int {
+	return int(math.Round(float64(v) * k))
+}
+
+func scenePoint(p image.Point) *ScenePoint {
+	return &ScenePoint{X: p.X, Y: p.Y}
+}
+
+func (p *ScenePoint) point() image.Point {
+	if p == nil {
+		return image.Point{}
+	}
+	return image.Pt(p.X, p.Y)
+}
+
+// Scene повертає документ з поточним станом сцени, враховуючи переміщення, повороти та масштабування фігур,
+// які ще не передано у цикл подій.
+func (u *Uistate) Scene() Scene {
+	scene := Scene{Version: SceneVersion}
+	if u.background != nil {
+		scene.Background = scenePaint(u.background)
+		scene.BackgroundClip = u.sceneRegion(u.backgroundClip)
+	}
+	if u.backgroundRectangle != nil {
+		rect := u.sceneShape(u.backgroundRectangle)
+		scene.Rectangle = &rect
+	}
+	for i, figure := range u.figuresArray {
+		shape := u.sceneShape(figure)
+		shape.ID = i + 1
+		scene.Figures = append(scene.Figures, shape)
+	}
+	for _, group := range u.groups.Shapes {
+		scene.Groups = append(scene.Groups, u.sceneShape(group))
+	}
+	u.projectPending(&scene)
+	return scene
+}
+
+// projectPending застосовує до документа відкладені операції, не змінюючи самих фігур.
+func (u *Uistate) projectPending(scene *Scene) {
+	figure := func(f *painter.CrossFigure) *SceneShape {
+		for i := range u.figuresArray {
+			if u.figuresArray[i] == f {
+				return &scene.Figures[i]
+			}
+		}
+		return nil
+	}
+	for _, op := range u.moveOperations {
+		switch op := op.(type) {
+		case *painter.MoveOperation:
+			for _, f := range op.FiguresArray {
+				if doc := figure(f); doc != nil {
+					doc.translate(op.X, op.Y)
+				}
+			}
+		case *painter.RotateOperation:
+			if doc := figure(op.Figure); doc != nil {
+				doc.Angle += op.Angle
+			}
+		case *painter.ScaleOperation:
+			if doc := figure(op.Figure); doc != nil {
+				doc.Scale = scaleOrDefault(doc.Scale) * op.Factor
+			}
+		case *painter.GroupMoveOperation:
+			if doc := findSceneGroup(scene.Groups, op.Group.Name); doc != nil {
+				doc.translate(op.X, op.Y)
+			}
+		}
+	}
+}
+
+func findSceneGroup(shapes []SceneShape, name string) *SceneShape {
+	for i := range shapes {
+		if shapes[i].Type != "group" {
+			continue
+		}
+		if shapes[i].Name == name {
+			return &shapes[i]
+		}
+		if found := findSceneGroup(shapes[i].Shapes, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// translate зсуває фігуру разом з її заливкою так само, як це робить painter.Shape.Translate.
+func (s *SceneShape) translate(dx, dy int) {
+	for _, p := range []*ScenePoint{s.From, s.To, s.Center} {
+		p.translate(dx, dy)
+	}
+	if s.Paint != nil {
+		for _, p := range []*ScenePoint{s.Paint.From, s.Paint.To, s.Paint.Center, s.Paint.Origin} {
+			p.translate(dx, dy)
+		}
+	}
+	for i := range s.Shapes {
+		s.Shapes[i].translate(dx, dy)
+	}
+}
+
+func (p *ScenePoint) translate(dx, dy int) {
+	if p != nil {
+		p.X, p.Y = p.X+dx, p.Y+dy
+	}
+}
+
+func (u *Uistate) sceneShape(s painter.Shape) SceneShape {
+	switch s := s.(type) {
+	case *painter.BackgroundRectangle:
+		return SceneShape{
+			Type:  "rectangle",
+			From:  scenePoint(s.FirstPoint),
+			To:    scenePoint(s.SecondPoint),
+			Angle: s.Angle,
+			Color: sceneColor(s.Color),
+			Paint: scenePaint(s.Paint),
+			Clip:  u.sceneRegion(s.Clip),
+		}
+	case *painter.CrossFigure:
+		return SceneShape{
+			Type:      "figure",
+			Center:    scenePoint(s.CentralPoint),
+			ArmLength: s.ArmLength,
+			ArmWidth:  s.ArmWidth,
+			Angle:     s.Angle,
+			Scale:     s.Scale,
+			Color:     sceneColor(s.Color),
+			Paint:     scenePaint(s.Paint),
+			Clip:      u.sceneRegion(s.Clip),
+		}
+	case *painter.Group:
+		group := SceneShape{Type: "group", Name: s.Name, Hidden: s.Hidden, Clip: u.sceneRegion(s.Clip)}
+		for _, inner := range s.Shapes {
+			group.Shapes = append(group.Shapes, u.sceneShape(inner))
+		}
+		return group
+	}
+	panic(fmt.Sprintf("unknown shape %T", s))
+}
+
+// sceneRegion описує область відсікання. Фігура, яку вже не можна знайти у сцені, замінюється її межами.
+func (u *Uistate) sceneRegion(r painter.Region) *SceneRegion {
+	if r == nil {
+		return nil
+	}
+	if target, ok := u.target(r); ok {
+		return &SceneRegion{Target: target}
+	}
+	b := r.Bounds()
+	return &SceneRegion{From: scenePoint(b.Min), To: scenePoint(b.Max)}
+}
+
+// target повертає назву, за якою фігуру можна знайти командами paint та clip.
+func (u *Uistate) target(r painter.Region) (string, bool) {
+	if shape, ok := r.(*painter.BackgroundRectangle); ok && shape == u.backgroundRectangle {
+		return "bgrect", true
+	}
+	for i, figure := range u.figuresArray {
+		if r == painter.Region(figure) {
+			return strconv.Itoa(i + 1), true
+		}
+	}
+	if group, ok := r.(*painter.Group); ok && u.groupsByName[group.Name] == group {
+		return group.Name, true
+	}
+	return "", false
+}
+
+func sceneColor(c color.Color) string {
+	if c == nil {
+		return ""
+	}
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A == 0xff {
+		return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", n.R, n.G, n.B, n.A)
+}
+
+func scenePaint(p painter.Paint) *ScenePaint {
+	switch p := p.(type) {
+	case painter.Solid:
+		return &ScenePaint{Type: "solid", Colors: []string{sceneColor(p.Color)}}
+	case painter.LinearGradient:
+		return &ScenePaint{
+			Type:   "linear",
+			Colors: []string{sceneColor(p.FromColor), sceneColor(p.ToColor)},
+			From:   scenePoint(p.From),
+			To:     scenePoint(p.To),
+		}
+	case painter.RadialGradient:
+		return &ScenePaint{
+			Type:   "radial",
+			Colors: []string{sceneColor(p.Inner), sceneColor(p.Outer)},
+			Center: scenePoint(p.Center),
+			Radius: p.Radius,
+		}
+	case painter.Checkerboard:
+		return &ScenePaint{
+			Type:   "checker",
+			Colors: []string{sceneColor(p.A), sceneColor(p.B)},
+			Origin: scenePoint(p.Origin),
+			Size:   p.Size,
+		}
+	case painter.Stripes:
+		return &ScenePaint{
+			Type:   "stripes",
+			Colors: []string{sceneColor(p.A), sceneColor(p.B)},
+			Origin: scenePoint(p.Origin),
+			Width:  p.Width,
+			Angle:  p.Angle,
+		}
+	case painter.Hatching:
+		return &ScenePaint{
+			Type:      "hatch",
+			Colors:    []string{sceneColor(p.Line), sceneColor(p.Background)},
+			Origin:    scenePoint(p.Origin),
+			Spacing:   p.Spacing,
+			LineWidth: p.LineWidth,
+			Angle:     p.Angle,
+		}
+	}
+	return nil
+}
+
+// SetScene замінює стан сцени документом. Якщо документ некоректний, стан залишається без змін.
+func (u *Uistate) SetScene(scene Scene) error {
+	if scene.Version < 1 || scene.Version > SceneVersion {
+		return fmt.Errorf("unsupported scene version %d", scene.Version)
+	}
+
+	var next Uistate
+	next.Reset()
+	// Області відсікання можуть посилатися на фігури, описані пізніше, тому вони розв'язуються після
+	// побудови всіх фігур.
+	var clips []func() error
+	build := func(doc SceneShape) (painter.Shape, error) {
+		return next.shapeFromScene(doc, &clips)
+	}
+
+	if scene.Rectangle != nil {
+		shape, err := build(*scene.Rectangle)
+		if err != nil {
+			return err
+		}
+		rect, ok := shape.(*painter.BackgroundRectangle)
+		if !ok {
+			return fmt.Errorf("scene rectangle has type %s", scene.Rectangle.Type)
+		}
+		next.backgroundRectangle = rect
+	}
+	for _, doc := range scene.Figures {
+		shape, err := build(doc)
+		if err != nil {
+			return err
+		}
+		figure, ok := shape.(*painter.CrossFigure)
+		if !ok {
+			return fmt.Errorf("scene figure has type %s", doc.Type)
+		}
+		next.figuresArray = append(next.figuresArray, figure)
+	}
+	for _, doc := range scene.Groups {
+		shape, err := build(doc)
+		if err != nil {
+			return err
+		}
+		if _, ok := shape.(*painter.Group); !ok {
+			return fmt.Errorf("scene group has type %s", doc.Type)
+		}
+		next.groups.Shapes = append(next.groups.Shapes, shape)
+	}
+	for _, resolve := range clips {
+		if err := resolve(); err != nil {
+			return err
+		}
+	}
+
+	next.backgroundColor = painter.OperationFunc(painter.Reset)
+	if scene.Background != nil {
+		paint, err := paintFromScene(scene.Background)
+		if err != nil {
+			return err
+		}
+		if next.clip, err = next.regionFromScene(scene.BackgroundClip); err != nil {
+			return err
+		}
+		next.PaintBackground(paint)
+		next.clip = nil
+	}
+
+	next.updateOperation = u.updateOperation
+	next.recordStart, next.recordStop = u.recordStart, u.recordStop
+	*u = next
+	return nil
+}
+
+func (u *Uistate) shapeFromScene(doc SceneShape, clips *[]func() error) (painter.Shape, error) {
+	var shape painter.Shape
+	var clip *painter.Region
+	switch doc.Type {
+	case "rectangle":
+		rect := &painter.BackgroundRectangle{FirstPoint: doc.From.point(), SecondPoint: doc.To.point(), Angle: doc.Angle}
+		shape, clip = rect, &rect.Clip
+	case "figure":
+		figure := &painter.CrossFigure{
+			CentralPoint: doc.Center.point(),
+			ArmLength:    doc.ArmLength,
+			ArmWidth:     doc.ArmWidth,
+			Angle:        doc.Angle,
+			Scale:        doc.Scale,
+		}
+		shape, clip = figure, &figure.Clip
+	case "group":
+		if _, ok := u.groupsByName[doc.Name]; ok || doc.Name == "" {
+			return nil, fmt.Errorf("invalid or duplicate group name '%s'", doc.Name)
+		}
+		if err := checkGroupName(doc.Name); err != nil {
+			return nil, err
+		}
+		group := &painter.Group{Name: doc.Name, Hidden: doc.Hidden}
+		if u.groupsByName == nil {
+			u.groupsByName = make(map[string]*painter.Group)
+		}
+		u.groupsByName[doc.Name] = group
+		for _, inner := range doc.Shapes {
+			s, err := u.shapeFromScene(inner, clips)
+			if err != nil {
+				return nil, err
+			}
+			group.Shapes = append(group.Shapes, s)
+		}
+		shape, clip = group, &group.Clip
+	default:
+		return nil, fmt.Errorf("unknown scene shape type '%s'", doc.Type)
+	}
+
+	if doc.Color != "" {
+		c, err := parseColor(doc.Color)
+		if err != nil {
+			return nil, err
+		}
+		shape.SetColor(c)
+	}
+	if doc.Paint != nil {
+		paint, err := paintFromScene(doc.Paint)
+		if err != nil {
+			return nil, err
+		}
+		shape.SetPaint(paint)
+	}
+	if doc.Clip != nil {
+		*clips = append(*clips, func() (err error) {
+			*clip, err = u.regionFromScene(doc.Clip)
+			return err
+		})
+	}
+	return shape, nil
+}
+
+func (u *Uistate) regionFromScene(doc *SceneRegion) (painter.Region, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	if doc.Target != "" {
+		return u.shape(doc.Target)
+	}
+	return painter.ClipRect(image.Rectangle{Min: doc.From.point(), Max: doc.To.point()}), nil
+}
+
+func paintFromScene(doc *ScenePaint) (painter.Paint, error) {
+	colors := make([]color.Color, len(doc.Colors))
+	for i, s := range doc.Colors {
+		c, err := parseColor(s)
+		if err != nil {
+			return nil, err
+		}
+		colors[i] = c
+	}
+	expected := 2
+	if doc.Type == "solid" {
+		expected = 1
+	}
+	if len(colors) != expected {
+		return nil, fmt.Errorf("%s paint needs %d colors", doc.Type, expected)
+	}
+
+	switch doc.Type {
+	case "solid":
+		return painter.Solid{Color: colors[0]}, nil
+	case "linear":
+		return painter.LinearGradient{From: doc.From.point(), To: doc.To.point(), FromColor: colors[0], ToColor: colors[1]}, nil
+	case "radial":
+		return painter.RadialGradient{Center: doc.Center.point(), Radius: doc.Radius, Inner: colors[0], Outer: colors[1]}, nil
+	case "checker":
+		return painter.Checkerboard{Origin: doc.Origin.point(), Size: doc.Size, A: colors[0], B: colors[1]}, nil
+	case "stripes":
+		return painter.Stripes{Origin: doc.Origin.point(), Width: doc.Width, Angle: doc.Angle, A: colors[0], B: colors[1]}, nil
+	case "hatch":
+		return painter.Hatching{
+			Origin:     doc.Origin.point(),
+			Spacing:    doc.Spacing,
+			LineWidth:  doc.LineWidth,
+			Angle:      doc.Angle,
+			Line:       colors[0],
+			Background: colors[1],
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown paint type '%s'", doc.Type)
+}
+
+// saveScene записує документ сцени у файл.
+func saveScene(path string, scene Scene) error {
+	data, err := json.MarshalIndent(scene, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadScene замінює стан сцени документом з файлу. Поточне перетворення та прямокутна область відсікання
+// залишаються чинними для подальших команд.
+func (u *Uistate) LoadScene(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var scene Scene
+	if err := json.Unmarshal(data, &scene); err != nil {
+		return fmt.Errorf("invalid scene file %s: %w", path, err)
+	}
+	transform, transforms, clip := u.transform, u.transforms, u.clip
+	if err := u.SetScene(scene); err != nil {
+		return err
+	}
+	u.transform, u.transforms = transform, transforms
+	// Фігури, якими могло бути задано відсікання, замінено фігурами з файлу.
+	if _, ok := clip.(painter.ClipRect); ok {
+		u.clip = clip
+	}
+	return nil
+}
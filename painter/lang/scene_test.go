@@ -0,0 +1,144 @@
+package lang
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sceneScript uses every kind of shape, paint and clipping region that a scene document can describe.
+const sceneScript = `fill linear 0 0 1 1 white #336699
+bgrect 0.1 0.1 0.4 0.4
+paint bgrect checker 0.05 black #ff000080
+figure 0.5 0.5 0.2 0.1 30 1.5
+clip 1
+figure 0.25 0.75
+unclip
+group g {
+  bgrect 0 0 0.1 0.1
+  group inner {
+    figure 0.75 0.25
+  }
+}
+color inner red
+clip 0 0 0.5 0.5
+paint g hatch 0.02 45 black white
+hide g`
+
+func TestScene_RoundTrip(t *testing.T) {
+	parser := &Parser{}
+	_, err := parser.Parse(strings.NewReader(sceneScript))
+	require.NoError(t, err)
+
+	scene := parser.Scene()
+	require.Len(t, scene.Figures, 2)
+	assert.Equal(t, &ScenePoint{X: 400, Y: 400}, scene.Figures[0].Center)
+	assert.Equal(t, &SceneRegion{Target: "1"}, scene.Figures[1].Clip)
+
+	data, err := json.Marshal(scene)
+	require.NoError(t, err)
+	var decoded Scene
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	loaded := &Parser{}
+	ops, err := loaded.SetScene(decoded)
+	require.NoError(t, err)
+	assert.NotEmpty(t, ops)
+	assert.Equal(t, scene, loaded.Scene())
+}
+
+func TestScene_SaveAndLoadCommands(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scene.json")
+
+	parser := &Parser{Dir: dir}
+	// The move is still pending when the scene is saved, but the saved scene already accounts for it.
+	_, err := parser.Parse(strings.NewReader("green\nfigure 0.5 0.5\nmove 0.125 0\nsave scene.json"))
+	require.NoError(t, err)
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+
+	// The current transform still applies to the figures added after the load.
+	loaded := &Parser{Dir: dir}
+	_, err = loaded.Parse(strings.NewReader("translate 0.125 0\nload scene.json\nfigure 0.25 0.25"))
+	require.NoError(t, err)
+	scene := loaded.Scene()
+	assert.Equal(t, "#00ff00", scene.Background.Colors[0])
+	require.Len(t, scene.Figures, 2)
+	assert.Equal(t, &ScenePoint{X: 500, Y: 400}, scene.Figures[0].Center)
+	assert.Equal(t, &ScenePoint{X: 300, Y: 200}, scene.Figures[1].Center)
+}
+
+func TestScene_SaveAndLoadRejected(t *testing.T) {
+	dir := t.TempDir()
+	parser := &Parser{Dir: dir}
+
+	// Nothing is written when a later line of the script is rejected, and the scene stays as it was.
+	_, err := parser.Parse(strings.NewReader("figure 0.5 0.5"))
+	require.NoError(t, err)
+	_, err = parser.Parse(strings.NewReader("green\nfigure 0.25 0.25\nsave scene.json\nbad"))
+	require.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, "scene.json"))
+	assert.True(t, os.IsNotExist(err))
+	scene := parser.Scene()
+	assert.Nil(t, scene.Background)
+	assert.Len(t, scene.Figures, 1)
+	assert.Equal(t, HistoryState{Position: 1, Length: 2}, parser.History())
+
+	for _, name := range []string{"../scene.json", "a/../../scene.json", filepath.Join(dir, "scene.json")} {
+		_, err = parser.Parse(strings.NewReader("save " + name))
+		assert.Error(t, err, name)
+		_, err = parser.Parse(strings.NewReader("load " + name))
+		assert.Error(t, err, name)
+	}
+
+	// Scene files are disabled without a directory.
+	_, err = (&Parser{}).Parse(strings.NewReader("save scene.json"))
+	assert.Error(t, err)
+}
+
+func TestRecord_PathsStayInDir(t *testing.T) {
+	dir := t.TempDir()
+	parser := &Parser{Dir: dir, Recorder: &painter.GIFRecorder{}}
+
+	ops, err := parser.Parse(strings.NewReader("record start anim.gif"))
+	require.NoError(t, err)
+	require.IsType(t, &painter.RecordOperation{}, ops[0])
+	assert.Equal(t, filepath.Join(dir, "anim.gif"), ops[0].(*painter.RecordOperation).Path)
+
+	var loop painter.Loop
+	handler := RecordHandler(&loop, parser)
+	for _, name := range []string{"../anim.gif", filepath.Join(dir, "anim.gif")} {
+		_, err = parser.Parse(strings.NewReader("record start " + name))
+		assert.Error(t, err, name)
+
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/record/start?file="+name, nil))
+		assert.Equal(t, http.StatusBadRequest, rw.Code, name)
+	}
+	assert.True(t, loop.Mq.Empty())
+}
+
+func TestScene_Invalid(t *testing.T) {
+	parser := &Parser{}
+	_, err := parser.Parse(strings.NewReader("figure 0.5 0.5"))
+	require.NoError(t, err)
+
+	_, err = parser.SetScene(Scene{Version: SceneVersion + 1})
+	assert.Error(t, err)
+	_, err = parser.SetScene(Scene{Version: SceneVersion, Figures: []SceneShape{{Type: "circle"}}})
+	assert.Error(t, err)
+	_, err = parser.SetScene(Scene{Version: SceneVersion, Figures: []SceneShape{{Type: "figure", Clip: &SceneRegion{Target: "7"}}}})
+	assert.Error(t, err)
+
+	// A rejected document leaves the current scene untouched.
+	assert.Len(t, parser.Scene().Figures, 1)
+}
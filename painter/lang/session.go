@@ -0,0 +1,98 @@
+package lang
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+)
+
+// RecordedRequest прийнята зміна сцени разом з часом її надходження від початку запису: скрипт або, для заміни
+// сцени, undo, redo та скриптів з командою load, отримана сцена.
+type RecordedRequest struct {
+	Offset time.Duration `json:"offset"`
+	Script string        `json:"script,omitempty"`
+	Scene  *Scene        `json:"scene,omitempty"`
+}
+
+// Recorder записує прийняті зміни сцени з усіх джерел (HTTP, WebSocket, TCP, stdin) разом з часом їх
+// надходження, щоб сесію можна було відтворити. Кожна зміна записується окремим JSON рядком. Щоб почати запис,
+// Recorder задається у полі Parser.Session.
+type Recorder struct {
+	mu    sync.Mutex
+	enc   *json.Encoder
+	start time.Time
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w), start: time.Now()}
+}
+
+// record записує прийняту зміну з поточним часом надходження.
+func (rec *Recorder) record(entry JournalEntry) error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.enc.Encode(RecordedRequest{Offset: time.Since(rec.start), Script: entry.Script, Scene: entry.Scene})
+}
+
+// Session налаштування відтворення записаної сесії.
+type Session struct {
+	// Speed множник швидкості відтворення; 0 означає оригінальну швидкість.
+	Speed float64
+	// Step, якщо заданий, викликається перед кожним запитом замість очікування, що дозволяє відтворювати сесію покроково.
+	Step func(r RecordedRequest)
+	// Sleep очікує задану тривалість; nil означає time.Sleep.
+	Sleep func(d time.Duration)
+}
+
+// Replay відтворює записану сесію: кожна зміна застосовується парсером у момент, що відповідає часу її надходження,
+// а отримані операції передаються у цикл подій. Команди save та record під час відтворення не виконуються, а
+// скрипти, які вже не вдається виконати, лише логуються.
+func (s Session) Replay(in io.Reader, p *Parser, loop *painter.Loop) error {
+	speed := s.Speed
+	if speed == 0 {
+		speed = 1
+	}
+	sleep := s.Sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+
+	defer p.startReplay()()
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 64*1024*1024)
+	start := time.Now()
+	for line := 1; scanner.Scan(); line++ {
+		var r RecordedRequest
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if s.Step != nil {
+			s.Step(r)
+		} else if wait := time.Duration(float64(r.Offset)/speed) - time.Since(start); wait > 0 {
+			sleep(wait)
+		}
+
+		var ops []painter.Operation
+		var err error
+		if r.Scene != nil {
+			ops, err = p.SetScene(*r.Scene)
+		} else {
+			ops, err = p.Parse(strings.NewReader(r.Script))
+		}
+		if err != nil {
+			log.Printf("Bad script: %s", err)
+			continue
+		}
+		loop.Post(painter.OperationList(ops))
+	}
+	return scanner.Err()
+}
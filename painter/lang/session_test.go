@@ -0,0 +1,71 @@
+package lang
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder_Session(t *testing.T) {
+	var out bytes.Buffer
+	var loop painter.Loop
+	dir := t.TempDir()
+	parser := &Parser{Session: NewRecorder(&out), Dir: dir}
+
+	// Changes from every entry point are recorded once they are accepted.
+	handler := HttpHandler(&loop, parser)
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/", strings.NewReader("figure 0.5 0.5")),
+		httptest.NewRequest(http.MethodGet, "/?cmd=update", nil),
+		httptest.NewRequest(http.MethodPost, "/", strings.NewReader("bad")),
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	_, err := parser.Parse(strings.NewReader("figure 0.25 0.25\nsave scene.json"))
+	require.NoError(t, err)
+	_, err = parser.Undo()
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(dir, "scene.json")))
+
+	var scripts []string
+	replayed, replayLoop := &Parser{Dir: dir}, &painter.Loop{}
+	session := Session{Step: func(r RecordedRequest) { scripts = append(scripts, r.Script) }}
+	require.NoError(t, session.Replay(strings.NewReader(out.String()), replayed, replayLoop))
+	assert.Equal(t, []string{"figure 0.5 0.5\n", "update\n", "figure 0.25 0.25\nsave scene.json\n", ""}, scripts)
+	assert.Equal(t, parser.Scene(), replayed.Scene())
+	assert.Len(t, replayLoop.Mq.Ops, 4)
+
+	// Replay does not repeat side effects such as saving files.
+	_, err = os.Stat(filepath.Join(dir, "scene.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSession_ReplayTiming(t *testing.T) {
+	in := `{"offset":0,"script":"white"}
+{"offset":2000000000,"script":"update"}
+`
+	var waits []time.Duration
+	session := Session{Speed: 4, Sleep: func(d time.Duration) { waits = append(waits, d) }}
+	require.NoError(t, session.Replay(strings.NewReader(in), &Parser{}, &painter.Loop{}))
+	require.Len(t, waits, 1)
+	assert.InDelta(t, float64(500*time.Millisecond), float64(waits[0]), float64(50*time.Millisecond))
+
+	assert.Error(t, session.Replay(strings.NewReader("not json"), &Parser{}, &painter.Loop{}))
+}
+
+func TestSession_ReplaySaveWithoutDir(t *testing.T) {
+	in := `{"offset":0,"script":"figure 0.5 0.5\nsave scene.json\nrecord start anim.gif\n"}
+`
+	replayed := &Parser{}
+	require.NoError(t, (&Session{}).Replay(strings.NewReader(in), replayed, &painter.Loop{}))
+	assert.Len(t, replayed.Scene().Figures, 1)
+}
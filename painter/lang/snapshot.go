@@ -0,0 +1,28 @@
+package lang
+
+import (
+	"image"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+)
+
+// Render малює сцену на текстурі у пам'яті та повертає отриманий кадр.
+func Render(scene Scene) (*image.RGBA, error) {
+	return RenderSize(scene, painter.CanvasSize)
+}
+
+// RenderSize малює сцену на квадратній текстурі зі стороною size пікселів. Сцена масштабується до малювання, тож
+// фігури малюються одразу у потрібній роздільності, а не розтягуються з готового кадру.
+func RenderSize(scene Scene, size int) (*image.RGBA, error) {
+	if size != painter.CanvasSize {
+		scene = scene.Scaled(float64(size) / painter.CanvasSize)
+	}
+	var p Parser
+	ops, err := p.SetScene(scene)
+	if err != nil {
+		return nil, err
+	}
+	t := painter.NewImageTexture(image.Pt(size, size))
+	painter.OperationList(ops).Do(t)
+	return t.Snapshot(), nil
+}
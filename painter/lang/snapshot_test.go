@@ -0,0 +1,40 @@
+package lang
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	parser := &Parser{}
+	_, err := parser.Parse(strings.NewReader("green\nbgrect 0 0 0.125 0.125"))
+	require.NoError(t, err)
+
+	img, err := Render(parser.Scene())
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{A: 0xff}, img.At(50, 50))
+	assert.Equal(t, color.RGBA{G: 0xff, A: 0xff}, img.At(150, 150))
+}
+
+func TestRenderSize(t *testing.T) {
+	parser := &Parser{}
+	_, err := parser.Parse(strings.NewReader("fill checker 0.125 white green\nbgrect 0 0 0.125 0.125\nfigure 0.5 0.5 0.25 0.125"))
+	require.NoError(t, err)
+	scene := parser.Scene()
+
+	// The scene is drawn at the requested size, so every pixel matches the full-size render of the same point.
+	full, err := Render(scene)
+	require.NoError(t, err)
+	img, err := RenderSize(scene, 1600)
+	require.NoError(t, err)
+	assert.Equal(t, 1600, img.Bounds().Dx())
+	for y := 0; y < 800; y += 7 {
+		for x := 0; x < 800; x += 7 {
+			require.Equal(t, full.At(x, y), img.At(2*x, 2*y), "%d,%d", x, y)
+		}
+	}
+}
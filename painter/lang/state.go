@@ -0,0 +1,155 @@
+package lang
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+)
+
+// stateSections розділи State, якими можна обмежити відповідь StateHandler.
+var stateSections = []string{"background", "rectangles", "figures", "groups"}
+
+// State стан сцени для клієнтів. На відміну від Scene, координати задаються в одиницях команд - частках сторони
+// полотна від 0 до 1, тож їх можна одразу використовувати у командах, наприклад для відносних переміщень.
+type State struct {
+	Version    int          `json:"version"`              // версія сцени: кількість прийнятих змін від запуску
+	Background string       `json:"background,omitempty"` // колір суцільного фону або тип заливки
+	Rectangles []StateShape `json:"rectangles,omitempty"`
+	Figures    []StateShape `json:"figures,omitempty"`
+	Groups     []StateShape `json:"groups,omitempty"`
+}
+
+// StateShape прямокутник, хрест або група.
+type StateShape struct {
+	ID     string      `json:"id,omitempty"`    // bgrect, номер фігури чи назва групи, за якими її знаходять команди
+	Group  string      `json:"group,omitempty"` // назва групи, до якої входить фігура
+	X      float64     `json:"x"`               // центр фігури
+	Y      float64     `json:"y"`
+	From   *StatePoint `json:"from,omitempty"` // кути прямокутника
+	To     *StatePoint `json:"to,omitempty"`
+	Angle  float64     `json:"angle,omitempty"`
+	Scale  float64     `json:"scale,omitempty"`
+	Color  string      `json:"color,omitempty"` // колір або тип заливки
+	Hidden bool        `json:"hidden,omitempty"`
+}
+
+type StatePoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// State повертає поточний стан сцени в одиницях команд.
+func (p *Parser) State() State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	state := p.uistate.State()
+	state.Version = p.version
+	return state
+}
+
+// State повертає стан сцени в одиницях команд без версії.
+func (u *Uistate) State() State {
+	scene := u.Scene()
+	var state State
+	if scene.Background != nil {
+		state.Background = paintName(scene.Background)
+	}
+	if scene.Rectangle != nil {
+		state.Rectangles = append(state.Rectangles, stateShape(*scene.Rectangle, "bgrect", ""))
+	}
+	for _, figure := range scene.Figures {
+		state.Figures = append(state.Figures, stateShape(figure, fmt.Sprint(figure.ID), ""))
+	}
+	var addGroup func(group SceneShape, parent string)
+	addGroup = func(group SceneShape, parent string) {
+		state.Groups = append(state.Groups, stateShape(group, group.Name, parent))
+		for _, shape := range group.Shapes {
+			switch shape.Type {
+			case "rectangle":
+				state.Rectangles = append(state.Rectangles, stateShape(shape, "", group.Name))
+			case "figure":
+				state.Figures = append(state.Figures, stateShape(shape, "", group.Name))
+			case "group":
+				addGroup(shape, group.Name)
+			}
+		}
+	}
+	for _, group := range scene.Groups {
+		addGroup(group, "")
+	}
+	return state
+}
+
+// Filter залишає у стані лише розділи sections; невідомий розділ спричиняє помилку.
+func (s State) Filter(sections []string) (State, error) {
+	filtered := State{Version: s.Version}
+	for _, section := range sections {
+		switch section {
+		case "background":
+			filtered.Background = s.Background
+		case "rectangles":
+			filtered.Rectangles = s.Rectangles
+		case "figures":
+			filtered.Figures = s.Figures
+		case "groups":
+			filtered.Groups = s.Groups
+		default:
+			return State{}, fmt.Errorf("unknown state section %q, expected one of %s", section, strings.Join(stateSections, ", "))
+		}
+	}
+	return filtered, nil
+}
+
+// StateHandler конструює обробник GET запитів, який повертає поточний стан сцени у форматі JSON. Параметр type
+// (можна повторювати або перелічувати через кому) обмежує відповідь розділами background, rectangles, figures та
+// groups.
+func StateHandler(p *Parser) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		state := p.State()
+		if types := r.URL.Query()["type"]; len(types) != 0 {
+			var err error
+			if state, err = state.Filter(strings.Split(strings.Join(types, ","), ",")); err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(state)
+	})
+}
+
+func stateShape(shape SceneShape, id, group string) StateShape {
+	s := StateShape{ID: id, Group: group, Angle: shape.Angle, Scale: shape.Scale, Color: shape.Color, Hidden: shape.Hidden}
+	if shape.Paint != nil {
+		s.Color = paintName(shape.Paint)
+	}
+	switch {
+	case shape.Center != nil:
+		s.X, s.Y = units(shape.Center.X), units(shape.Center.Y)
+	case shape.From != nil && shape.To != nil:
+		s.From = &StatePoint{X: units(shape.From.X), Y: units(shape.From.Y)}
+		s.To = &StatePoint{X: units(shape.To.X), Y: units(shape.To.Y)}
+		s.X, s.Y = (s.From.X+s.To.X)/2, (s.From.Y+s.To.Y)/2
+	}
+	return s
+}
+
+// paintName повертає колір суцільної заливки або тип іншої заливки.
+func paintName(paint *ScenePaint) string {
+	if paint.Type == "solid" && len(paint.Colors) == 1 {
+		return paint.Colors[0]
+	}
+	return paint.Type
+}
+
+// units переводить пікселі текстури в одиниці команд.
+func units(px int) float64 {
+	return float64(px) / painter.CanvasSize
+}
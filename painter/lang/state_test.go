@@ -0,0 +1,63 @@
+package lang
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_State(t *testing.T) {
+	p := &Parser{}
+	_, err := p.Parse(strings.NewReader("green\nbgrect 0.25 0.25 0.75 0.5\nfigure 0.5 0.5\ngroup g {\nfigure 0.25 0.75\n}"))
+	require.NoError(t, err)
+
+	state := p.State()
+	assert.Equal(t, 1, state.Version)
+	assert.Equal(t, "#00ff00", state.Background)
+	require.Len(t, state.Rectangles, 1)
+	assert.Equal(t, StateShape{ID: "bgrect", X: 0.5, Y: 0.375, From: &StatePoint{X: 0.25, Y: 0.25}, To: &StatePoint{X: 0.75, Y: 0.5}}, state.Rectangles[0])
+	require.Len(t, state.Figures, 2)
+	assert.Equal(t, StateShape{ID: "1", X: 0.5, Y: 0.5}, state.Figures[0])
+	assert.Equal(t, StateShape{Group: "g", X: 0.25, Y: 0.75}, state.Figures[1])
+	assert.Equal(t, []StateShape{{ID: "g"}}, state.Groups)
+
+	// Moves of an unflushed batch are not applied to the scene yet.
+	batch := Batch{Parser: p}
+	require.NoError(t, batch.Exec("move 0.125 0"))
+	require.NoError(t, batch.Exec("rotate 1 45"))
+	state = p.State()
+	assert.Equal(t, 0.5, state.Figures[0].X)
+
+	_, err = batch.Flush()
+	require.NoError(t, err)
+	state = p.State()
+	assert.Equal(t, 0.625, state.Figures[0].X)
+	assert.Equal(t, 45.0, state.Figures[0].Angle)
+}
+
+func TestStateHandler(t *testing.T) {
+	p := &Parser{}
+	_, err := p.Parse(strings.NewReader("white\nbgrect 0 0 0.5 0.5\nfigure 0.5 0.5"))
+	require.NoError(t, err)
+	server := httptest.NewServer(StateHandler(p))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?type=figures,background")
+	require.NoError(t, err)
+	var state State
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&state))
+	resp.Body.Close()
+	assert.Equal(t, "#ffffff", state.Background)
+	assert.Len(t, state.Figures, 1)
+	assert.Empty(t, state.Rectangles)
+
+	resp, err = http.Get(server.URL + "?type=circles")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,286 @@
+package lang
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+)
+
+// Кольори, якими painter малює фон та фігури без явно заданого кольору.
+const (
+	svgDefaultBackground = "#000000"
+	svgDefaultRectangle  = "#000000"
+	svgDefaultFigure     = "#ffff00"
+)
+
+// WriteSVG записує сцену як SVG документ: фон, прямокутник, хрести та групи стають окремими SVG елементами,
+// заливки - градієнтами та візерунками, а області відсікання - елементами clipPath.
+func WriteSVG(w io.Writer, scene Scene) error {
+	s := &svgWriter{scene: scene}
+
+	fill := svgFill(svgDefaultBackground)
+	clip := ""
+	if scene.Background != nil {
+		fill = s.paint(scene.Background, svgDefaultBackground)
+		clip = s.clip(scene.BackgroundClip)
+	}
+	fmt.Fprintf(&s.body, `  <rect id="background" width="%d" height="%d" %s%s/>`+"\n", painter.CanvasSize, painter.CanvasSize, fill, clip)
+	if scene.Rectangle != nil {
+		s.shape(*scene.Rectangle, "bgrect", "  ")
+	}
+	for _, figure := range scene.Figures {
+		s.shape(figure, "figure-"+strconv.Itoa(figure.ID), "  ")
+	}
+	for _, group := range scene.Groups {
+		s.shape(group, "", "  ")
+	}
+
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		painter.CanvasSize, painter.CanvasSize, painter.CanvasSize, painter.CanvasSize)
+	if err != nil {
+		return err
+	}
+	if s.defs.Len() != 0 {
+		fmt.Fprintf(w, "  <defs>\n%s  </defs>\n", s.defs.String())
+	}
+	_, err = fmt.Fprintf(w, "%s</svg>\n", s.body.String())
+	return err
+}
+
+type svgWriter struct {
+	scene  Scene
+	defs   bytes.Buffer
+	body   bytes.Buffer
+	nextID int
+}
+
+// svgRect прямокутник, повернутий на angle градусів навколо свого центру.
+type svgRect struct {
+	r     image.Rectangle
+	angle float64
+}
+
+func (r svgRect) String() string {
+	s := fmt.Sprintf(`x="%d" y="%d" width="%d" height="%d"`, r.r.Min.X, r.r.Min.Y, r.r.Dx(), r.r.Dy())
+	if r.angle != 0 {
+		c := r.r.Min.Add(r.r.Max)
+		s += fmt.Sprintf(` transform="rotate(%s %s %s)"`, svgNumber(r.angle), svgNumber(float64(c.X)/2), svgNumber(float64(c.Y)/2))
+	}
+	return s
+}
+
+// rects повертає прямокутники, з яких складається фігура.
+func (s *svgWriter) rects(shape SceneShape) []svgRect {
+	switch shape.Type {
+	case "rectangle":
+		return []svgRect{{r: image.Rectangle{Min: shape.From.point(), Max: shape.To.point()}.Canon(), angle: shape.Angle}}
+	case "figure":
+		scale := shape.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		length, width := float64(shape.ArmLength), float64(shape.ArmWidth)
+		if length == 0 {
+			length = painter.DefaultArmLength
+		}
+		if width == 0 {
+			width = painter.DefaultArmWidth
+		}
+		c := shape.Center.point()
+		arm := func(w, h float64) svgRect {
+			hw, hh := int(w*scale/2+0.5), int(h*scale/2+0.5)
+			return svgRect{r: image.Rect(c.X-hw, c.Y-hh, c.X+hw, c.Y+hh), angle: shape.Angle}
+		}
+		return []svgRect{arm(length, width), arm(width, length)}
+	case "group":
+		var rects []svgRect
+		for _, inner := range shape.Shapes {
+			rects = append(rects, s.rects(inner)...)
+		}
+		return rects
+	}
+	return nil
+}
+
+func (s *svgWriter) shape(shape SceneShape, id, indent string) {
+	clip := s.clip(shape.Clip)
+	switch shape.Type {
+	case "rectangle":
+		fill := s.paintOrColor(shape, svgDefaultRectangle)
+		fmt.Fprintf(&s.body, `%s<rect%s %s %s%s/>`+"\n", indent, svgID(id), s.rects(shape)[0], fill, clip)
+	case "figure":
+		fill := s.paintOrColor(shape, svgDefaultFigure)
+		fmt.Fprintf(&s.body, `%s<g%s %s%s>`+"\n", indent, svgID(id), fill, clip)
+		for _, r := range s.rects(shape) {
+			fmt.Fprintf(&s.body, "%s  <rect %s/>\n", indent, r)
+		}
+		fmt.Fprintf(&s.body, "%s</g>\n", indent)
+	case "group":
+		hidden := ""
+		if shape.Hidden {
+			hidden = ` display="none"`
+		}
+		fmt.Fprintf(&s.body, `%s<g%s%s%s>`+"\n", indent, svgID("group-"+shape.Name), hidden, clip)
+		for _, inner := range shape.Shapes {
+			s.shape(inner, "", indent+"  ")
+		}
+		fmt.Fprintf(&s.body, "%s</g>\n", indent)
+	}
+}
+
+func (s *svgWriter) paintOrColor(shape SceneShape, fallback string) string {
+	if shape.Paint != nil {
+		return s.paint(shape.Paint, fallback)
+	}
+	if shape.Color != "" {
+		return svgFill(shape.Color)
+	}
+	return svgFill(fallback)
+}
+
+// paint повертає атрибути заливки, за потреби додаючи градієнт чи візерунок у defs.
+func (s *svgWriter) paint(p *ScenePaint, fallback string) string {
+	colors := append(append([]string{}, p.Colors...), fallback, fallback)
+	a, b := colors[0], colors[1]
+	if p.Type == "solid" {
+		return svgFill(a)
+	}
+
+	s.nextID++
+	id := fmt.Sprintf("paint%d", s.nextID)
+	origin := p.Origin.point()
+	switch p.Type {
+	case "linear":
+		from, to := p.From.point(), p.To.point()
+		fmt.Fprintf(&s.defs, `    <linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%d" y1="%d" x2="%d" y2="%d">`+"\n",
+			id, from.X, from.Y, to.X, to.Y)
+		fmt.Fprintf(&s.defs, "      <stop offset=\"0\" %s/>\n      <stop offset=\"1\" %s/>\n", svgStop(a), svgStop(b))
+		fmt.Fprintf(&s.defs, "    </linearGradient>\n")
+	case "radial":
+		center := p.Center.point()
+		fmt.Fprintf(&s.defs, `    <radialGradient id="%s" gradientUnits="userSpaceOnUse" cx="%d" cy="%d" r="%d">`+"\n",
+			id, center.X, center.Y, p.Radius)
+		fmt.Fprintf(&s.defs, "      <stop offset=\"0\" %s/>\n      <stop offset=\"1\" %s/>\n", svgStop(a), svgStop(b))
+		fmt.Fprintf(&s.defs, "    </radialGradient>\n")
+	case "checker":
+		fmt.Fprintf(&s.defs, `    <pattern id="%s" patternUnits="userSpaceOnUse" x="%d" y="%d" width="%d" height="%d">`+"\n",
+			id, origin.X, origin.Y, 2*p.Size, 2*p.Size)
+		fmt.Fprintf(&s.defs, "      <rect width=\"%d\" height=\"%d\" %s/>\n", 2*p.Size, 2*p.Size, svgFill(b))
+		fmt.Fprintf(&s.defs, "      <rect width=\"%d\" height=\"%d\" %s/>\n", p.Size, p.Size, svgFill(a))
+		fmt.Fprintf(&s.defs, "      <rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" %s/>\n", p.Size, p.Size, p.Size, p.Size, svgFill(a))
+		fmt.Fprintf(&s.defs, "    </pattern>\n")
+	case "stripes":
+		fmt.Fprintf(&s.defs, `    <pattern id="%s" patternUnits="userSpaceOnUse" width="%d" height="%d" patternTransform="translate(%d %d) rotate(%s)">`+"\n",
+			id, 2*p.Width, 2*p.Width, origin.X, origin.Y, svgNumber(p.Angle))
+		fmt.Fprintf(&s.defs, "      <rect width=\"%d\" height=\"%d\" %s/>\n", 2*p.Width, p.Width, svgFill(a))
+		fmt.Fprintf(&s.defs, "      <rect y=\"%d\" width=\"%d\" height=\"%d\" %s/>\n", p.Width, 2*p.Width, p.Width, svgFill(b))
+		fmt.Fprintf(&s.defs, "    </pattern>\n")
+	case "hatch":
+		// Для штриховки перший колір - колір ліній, другий - колір фону.
+		fmt.Fprintf(&s.defs, `    <pattern id="%s" patternUnits="userSpaceOnUse" width="%d" height="%d" patternTransform="translate(%d %d) rotate(%s)">`+"\n",
+			id, p.Spacing, p.Spacing, origin.X, origin.Y, svgNumber(p.Angle))
+		fmt.Fprintf(&s.defs, "      <rect width=\"%d\" height=\"%d\" %s/>\n", p.Spacing, p.Spacing, svgFill(b))
+		fmt.Fprintf(&s.defs, "      <rect width=\"%d\" height=\"%d\" %s/>\n", p.Spacing, p.LineWidth, svgFill(a))
+		fmt.Fprintf(&s.defs, "    </pattern>\n")
+	default:
+		return svgFill(fallback)
+	}
+	return `fill="url(#` + id + `)"`
+}
+
+// clip повертає атрибут clip-path для області відсікання, додаючи відповідний clipPath у defs.
+func (s *svgWriter) clip(r *SceneRegion) string {
+	if r == nil {
+		return ""
+	}
+	var rects []svgRect
+	if r.Target != "" {
+		if target, ok := s.target(r.Target); ok {
+			rects = s.rects(target)
+		}
+	} else {
+		rects = []svgRect{{r: image.Rectangle{Min: r.From.point(), Max: r.To.point()}.Canon()}}
+	}
+
+	s.nextID++
+	id := fmt.Sprintf("clip%d", s.nextID)
+	fmt.Fprintf(&s.defs, "    <clipPath id=\"%s\">\n", id)
+	for _, rect := range rects {
+		fmt.Fprintf(&s.defs, "      <rect %s/>\n", rect)
+	}
+	fmt.Fprintf(&s.defs, "    </clipPath>\n")
+	return ` clip-path="url(#` + id + `)"`
+}
+
+// target шукає фігуру сцени за назвою, яку використовують команди paint та clip.
+func (s *svgWriter) target(name string) (SceneShape, bool) {
+	if name == "bgrect" && s.scene.Rectangle != nil {
+		return *s.scene.Rectangle, true
+	}
+	if id, err := strconv.Atoi(name); err == nil {
+		for _, figure := range s.scene.Figures {
+			if figure.ID == id {
+				return figure, true
+			}
+		}
+		return SceneShape{}, false
+	}
+	var find func(shapes []SceneShape) (SceneShape, bool)
+	find = func(shapes []SceneShape) (SceneShape, bool) {
+		for _, shape := range shapes {
+			if shape.Type != "group" {
+				continue
+			}
+			if shape.Name == name {
+				return shape, true
+			}
+			if found, ok := find(shape.Shapes); ok {
+				return found, true
+			}
+		}
+		return SceneShape{}, false
+	}
+	return find(s.scene.Groups)
+}
+
+func svgID(id string) string {
+	if id == "" {
+		return ""
+	}
+	return ` id="` + svgAttr(id) + `"`
+}
+
+func svgAttr(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// svgFill повертає атрибути заливки кольором c, а svgStop - атрибути кольору точки градієнта.
+func svgFill(c string) string { return svgColor("fill", "fill-opacity", c) }
+func svgStop(c string) string { return svgColor("stop-color", "stop-opacity", c) }
+
+// svgColor повертає атрибут attr з кольором c. Не всі програми перегляду SVG розуміють кольори виду #rrggbbaa, тож
+// прозорість записується окремим атрибутом opacity.
+func svgColor(attr, opacity, c string) string {
+	if len(c) == 9 && c[0] == '#' {
+		if alpha, err := strconv.ParseUint(c[7:], 16, 8); err == nil {
+			result := attr + `="` + svgAttr(c[:7]) + `"`
+			if alpha != 0xff {
+				result += " " + opacity + `="` + svgNumber(math.Round(float64(alpha)/0xff*1000)/1000) + `"`
+			}
+			return result
+		}
+	}
+	return attr + `="` + svgAttr(c) + `"`
+}
+
+func svgNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,48 @@
+package lang
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSVG(t *testing.T) {
+	parser := &Parser{}
+	_, err := parser.Parse(strings.NewReader(sceneScript))
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteSVG(&out, parser.Scene()))
+	svg := out.String()
+
+	// The document must be well-formed XML.
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			assert.Equal(t, "EOF", err.Error())
+			break
+		}
+	}
+
+	assert.Contains(t, svg, `<linearGradient id="paint1" gradientUnits="userSpaceOnUse" x1="0" y1="0" x2="800" y2="800">`)
+	assert.Contains(t, svg, `<rect id="background" width="800" height="800" fill="url(#paint1)"/>`)
+	assert.Contains(t, svg, `<g id="figure-1" fill="#ffff00">`)
+	assert.Contains(t, svg, `<rect x="280" y="340" width="240" height="120" transform="rotate(30 400 400)"/>`)
+	assert.Contains(t, svg, `<g id="figure-2" fill="#ffff00" clip-path="url(#clip3)">`)
+	assert.Contains(t, svg, `<clipPath id="clip3">`)
+	assert.Contains(t, svg, `<g id="group-g" display="none">`)
+	assert.Contains(t, svg, `<g id="group-inner">`)
+	// Translucent colors are written as #rrggbb with a separate opacity.
+	assert.Contains(t, svg, `<rect width="80" height="80" fill="#ff0000" fill-opacity="0.502"/>`)
+	assert.NotContains(t, svg, "#ff000080")
+}
+
+func TestSVGColor(t *testing.T) {
+	assert.Equal(t, `fill="#336699"`, svgFill("#336699"))
+	assert.Equal(t, `fill="#336699"`, svgFill("#336699ff"))
+	assert.Equal(t, `stop-color="#336699" stop-opacity="0"`, svgStop("#33669900"))
+}
@@ -0,0 +1,141 @@
+package lang
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+)
+
+// Batch пакет команд, які перевіряються по одній на окремій копії сцени Parser, а до самої сцени застосовуються
+// разом під час Flush, тож інші клієнти не бачать пакет виконаним частково. Нульове значення з заданим Parser
+// готове до використання.
+type Batch struct {
+	Parser *Parser
+
+	script strings.Builder
+	stage  *Parser // копія сцени та історії Parser, на якій виконуються команди пакета до Flush
+}
+
+// Exec виконує одну команду пакета на копії сцени. Порожні рядки ігноруються.
+func (b *Batch) Exec(cmdl string) error {
+	if strings.TrimSpace(cmdl) == "" {
+		return nil
+	}
+	if b.stage == nil {
+		b.Parser.mu.Lock()
+		b.stage = b.Parser.staging()
+		b.Parser.mu.Unlock()
+		b.stage.begin()
+	}
+
+	s := b.stage
+	before, position := s.uistate.clone(), s.history.position
+	if err := s.parse(cmdl); err != nil {
+		s.uistate, s.history.position = before, position
+		b.Parser.mu.Lock()
+		defer b.Parser.mu.Unlock()
+		return b.Parser.reject(err)
+	}
+	b.script.WriteString(cmdl + "\n")
+	return nil
+}
+
+// Flush виконує накопичені команди над сценою Parser одним скриптом і повертає операції, що її малюють; для
+// порожнього пакета повертає nil. Якщо сцену тим часом змінено так, що пакет уже не виконується, він відхиляється
+// цілком.
+func (b *Batch) Flush() ([]painter.Operation, error) {
+	if b.stage == nil {
+		return nil, nil
+	}
+	script := b.script.String()
+	b.script.Reset()
+	b.stage = nil
+	return b.Parser.Parse(strings.NewReader(script))
+}
+
+// ServeTCP приймає з'єднання з l та виконує рядковий протокол: кожен рядок - команда, яку виконує p, а порожній
+// рядок або команда update передає накопичений пакет у painter.Loop. На кожен рядок сервер відповідає рядком OK
+// або ERR з причиною помилки. Протокол не перевіряє токени: кожне з'єднання отримує рівень доступу granted, а
+// команди, що потребують вищого, відхиляються. limits (nil вимикає обмеження) обмежує кількість команд у пакеті
+// та частоту пакетів з однієї адреси. Повертає помилку, коли l закрито.
+func ServeTCP(l net.Listener, loop *painter.Loop, p *Parser, granted Permission, limits *Limits) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go serveLines(conn, loop, p, granted, limits)
+	}
+}
+
+func serveLines(conn net.Conn, loop *painter.Loop, p *Parser, granted Permission, limits *Limits) {
+	defer conn.Close()
+	batch := Batch{Parser: p}
+	commands := 0 // кількість команд у поточному пакеті
+	flush := func() error {
+		commands = 0
+		ops, err := batch.Flush()
+		if err == nil && ops != nil {
+			loop.Post(painter.OperationList(ops))
+		}
+		return err
+	}
+	// Команди, прийняті до розриву з'єднання, застосовуються так само, як після порожнього рядка.
+	defer flush()
+
+	scanner := bufio.NewScanner(conn)
+	out := bufio.NewWriter(conn)
+	for scanner.Scan() {
+		cmdl := strings.TrimSpace(scanner.Text())
+		err := checkLine(cmdl, commands, granted, limits, conn.RemoteAddr().String())
+		if err == nil {
+			err = batch.Exec(cmdl)
+		}
+		if err == nil && cmdl != "" {
+			commands++
+		}
+		if err == nil && (cmdl == "" || cmdl == "update") {
+			err = flush()
+		}
+
+		if err != nil {
+			fmt.Fprintf(out, "ERR %s\n", err)
+		} else {
+			fmt.Fprint(out, "OK\n")
+		}
+		if out.Flush() != nil {
+			return
+		}
+	}
+}
+
+// checkLine перевіряє, чи можна додати команду cmdl до пакета, у якому вже є commands команд.
+func checkLine(cmdl string, commands int, granted Permission, limits *Limits, addr string) error {
+	if cmdl == "" {
+		return nil
+	}
+	if scriptPermission(cmdl) > granted {
+		return fmt.Errorf("command is not allowed over this connection")
+	}
+	if limits == nil {
+		return nil
+	}
+	if limits.MaxCommands > 0 && commands >= limits.MaxCommands {
+		return fmt.Errorf("batch has %d commands, at most %d are allowed", commands, limits.MaxCommands)
+	}
+	// Частота рахується для пакетів, тож перевіряється на першій команді кожного з них.
+	if commands == 0 {
+		if wait, ok := limits.allow(remoteHost(addr)); !ok {
+			return fmt.Errorf("too many requests, retry in %s", wait.Round(time.Second))
+		}
+	}
+	return nil
+}
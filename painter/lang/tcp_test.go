@@ -0,0 +1,104 @@
+package lang
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeTCP(t *testing.T) {
+	var loop painter.Loop
+	parser := &Parser{}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() { done <- ServeTCP(l, &loop, parser, PermissionAdmin, nil) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	replies := bufio.NewScanner(conn)
+	send := func(line string) string {
+		_, err := fmt.Fprintln(conn, line)
+		require.NoError(t, err)
+		require.True(t, replies.Scan())
+		return replies.Text()
+	}
+
+	assert.Equal(t, "OK", send("white"))
+	assert.Equal(t, "OK", send("group g {"))
+	assert.Equal(t, "OK", send("figure 0.5 0.5"))
+	assert.Equal(t, "OK", send("}"))
+	assert.Equal(t, "ERR invalid command bad", send("bad"))
+	assert.Empty(t, loop.Mq.Ops, "nothing is posted before the batch is flushed")
+
+	assert.Equal(t, "OK", send("update"))
+	assert.Len(t, loop.Mq.Ops, 1)
+	assert.Len(t, parser.Scene().Groups, 1)
+
+	// A blank line flushes the batch without presenting a frame.
+	assert.Equal(t, "OK", send("green"))
+	assert.Equal(t, "OK", send(""))
+	assert.Len(t, loop.Mq.Ops, 2)
+	assert.Equal(t, "OK", send(""), "an empty batch is not posted")
+	assert.Len(t, loop.Mq.Ops, 2)
+
+	assert.Equal(t, "OK", send("group h {"))
+	assert.Equal(t, "ERR group h is not closed", send(""))
+
+	require.NoError(t, l.Close())
+	assert.NoError(t, <-done)
+}
+
+func TestBatch_IsolatedUntilFlush(t *testing.T) {
+	p := &Parser{}
+	batch := Batch{Parser: p}
+	require.NoError(t, batch.Exec("group g {"))
+	require.NoError(t, batch.Exec("figure 0.5 0.5"))
+
+	// Another client's script neither sees nor disturbs the half-built batch.
+	_, err := p.Parse(strings.NewReader("figure 0.25 0.25"))
+	require.NoError(t, err)
+	assert.Empty(t, p.Scene().Groups)
+
+	require.NoError(t, batch.Exec("}"))
+	_, err = batch.Flush()
+	require.NoError(t, err)
+	scene := p.Scene()
+	assert.Len(t, scene.Figures, 1)
+	assert.Len(t, scene.Groups, 1)
+	assert.Equal(t, HistoryState{Position: 2, Length: 3}, p.History())
+}
+
+func TestServeTCP_PermissionAndLimits(t *testing.T) {
+	var loop painter.Loop
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go ServeTCP(l, &loop, &Parser{}, PermissionDraw, &Limits{MaxCommands: 2})
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	replies := bufio.NewScanner(conn)
+	send := func(line string) string {
+		_, err := fmt.Fprintln(conn, line)
+		require.NoError(t, err)
+		require.True(t, replies.Scan())
+		return replies.Text()
+	}
+
+	assert.Equal(t, "ERR command is not allowed over this connection", send("reset"))
+	assert.Equal(t, "OK", send("white"))
+	assert.Equal(t, "OK", send("figure 0.5 0.5"))
+	assert.Equal(t, "ERR batch has 2 commands, at most 2 are allowed", send("green"))
+	// The limit applies to each batch.
+	assert.Equal(t, "OK", send(""))
+	assert.Equal(t, "OK", send("green"))
+}
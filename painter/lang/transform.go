@@ -0,0 +1,59 @@
+package lang
+
+import (
+	"image"
+	"math"
+)
+
+// transform афінне перетворення координат: x' = a*x + c*y + e, y' = b*x + d*y + f.
+// Парсер підтримує лише перенесення, поворот та рівномірне масштабування, тому перетворення завжди
+// зберігає форму фігур і може бути розкладене на кут повороту та масштаб.
+type transform struct {
+	a, b, c, d, e, f float64
+}
+
+var identity = transform{a: 1, d: 1}
+
+func translation(dx, dy float64) transform {
+	return transform{a: 1, d: 1, e: dx, f: dy}
+}
+
+func rotation(angle float64) transform {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	return transform{a: cos, b: sin, c: -sin, d: cos}
+}
+
+func scaling(factor float64) transform {
+	return transform{a: factor, d: factor}
+}
+
+// multiply повертає добуток m*n: перетворення, яке спочатку застосовує n, а потім m.
+func (m transform) multiply(n transform) transform {
+	return transform{
+		a: m.a*n.a + m.c*n.b,
+		b: m.b*n.a + m.d*n.b,
+		c: m.a*n.c + m.c*n.d,
+		d: m.b*n.c + m.d*n.d,
+		e: m.a*n.e + m.c*n.f + m.e,
+		f: m.b*n.e + m.d*n.f + m.f,
+	}
+}
+
+func (m transform) apply(x, y float64) (float64, float64) {
+	return m.a*x + m.c*y + m.e, m.b*x + m.d*y + m.f
+}
+
+func (m transform) applyPoint(p image.Point) image.Point {
+	x, y := m.apply(float64(p.X), float64(p.Y))
+	return image.Pt(int(math.Round(x)), int(math.Round(y)))
+}
+
+// angle повертає кут повороту перетворення у градусах.
+func (m transform) angle() float64 {
+	return math.Atan2(m.b, m.a) * 180 / math.Pi
+}
+
+// scale повертає коефіцієнт масштабування перетворення.
+func (m transform) scale() float64 {
+	return math.Hypot(m.a, m.b)
+}
@@ -1,47 +1,90 @@
 package lang
 
 import (
-	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"fmt"
 	"image"
+	"image/color"
+	"math"
+	"strconv"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
 )
 
 type Uistate struct {
 	backgroundColor     painter.Operation
+	background          painter.Paint  // заливка фону, з якої побудовано backgroundColor
+	backgroundClip      painter.Region // область відсікання фону
 	backgroundRectangle *painter.BackgroundRectangle
 	figuresArray        []*painter.CrossFigure
+	groups              painter.Group             // групи верхнього рівня
+	groupsByName        map[string]*painter.Group // усі групи, включно з вкладеними
+	openGroups          []*painter.Group          // групи, оголошення яких ще не закрито
 	moveOperations      []painter.Operation
 	updateOperation     painter.Operation
+
+	// Перетворення та відсікання діють лише до кінця скрипту, тож скрипти різних клієнтів не впливають одне на одне.
+	transform  transform   // поточне перетворення координат нових фігур
+	transforms []transform // стек збережених перетворень
+
+	clip painter.Region // область відсікання для нових фігур та фону
+
+	recordStart painter.Operation // початок запису анімації, що виконується перед рештою операцій
+	recordStop  painter.Operation // завершення запису, що виконується після оновлення
 }
 
 func (u *Uistate) Reset() {
 	u.backgroundColor = nil
+	u.background = nil
+	u.backgroundClip = nil
 	u.backgroundRectangle = nil
 	u.figuresArray = nil
+	u.groups = painter.Group{}
+	u.groupsByName = nil
+	u.openGroups = nil
 	u.moveOperations = nil
 	u.updateOperation = nil
+	u.transform = identity
+	u.transforms = nil
+	u.clip = nil
 }
 
+// GetOperations повертає операції, що малюють сцену, та застосовує до неї відкладені переміщення. Операції
+// посилаються на копії фігур, тож цикл подій не змінює і не читає стан, з яким працює парсер.
 func (u *Uistate) GetOperations() []painter.Operation {
 	var ops []painter.Operation
+	c := shapeCopier{}
 
+	if u.recordStart != nil {
+		ops = append(ops, u.recordStart)
+		u.recordStart = nil
+	}
 	if u.backgroundColor != nil {
-		ops = append(ops, u.backgroundColor)
+		ops = append(ops, c.operation(u.backgroundColor))
 	}
 	if u.backgroundRectangle != nil {
-		ops = append(ops, u.backgroundRectangle)
+		ops = append(ops, c.shape(u.backgroundRectangle))
 	}
-	if len(u.moveOperations) != 0 {
-		ops = append(ops, u.moveOperations...)
-		u.moveOperations = nil
+	for _, op := range u.moveOperations {
+		ops = append(ops, c.operation(op))
 	}
-	if len(u.figuresArray) != 0 {
-		for _, figure := range u.figuresArray {
-			ops = append(ops, figure)
-		}
+	for _, figure := range u.figuresArray {
+		ops = append(ops, c.shape(figure))
+	}
+	for _, group := range u.groups.Shapes {
+		ops = append(ops, c.shape(group))
 	}
+	// Копії зроблено до переміщень: їх зсувають операції переміщення у циклі подій, а модель - цей цикл.
+	for _, op := range u.moveOperations {
+		op.Do(nil)
+	}
+	u.moveOperations = nil
 	if u.updateOperation != nil {
 		ops = append(ops, u.updateOperation)
 	}
+	if u.recordStop != nil {
+		ops = append(ops, u.recordStop)
+		u.recordStop = nil
+	}
 
 	return ops
 }
@@ -53,28 +96,300 @@ func (u *Uistate) ResetOperations() {
 	if u.updateOperation != nil {
 		u.updateOperation = nil
 	}
+	u.openGroups = nil
+}
+
+// ResetDrawing скидає перетворення координат, їхній стек та область відсікання.
+func (u *Uistate) ResetDrawing() {
+	u.transform = identity
+	u.transforms = nil
+	u.clip = nil
 }
 
 func (u *Uistate) GreenBackground() {
-	u.backgroundColor = painter.OperationFunc(painter.GreenFill)
+	u.PaintBackground(painter.Solid{Color: namedColors["green"]})
 }
 
 func (u *Uistate) WhiteBackground() {
-	u.backgroundColor = painter.OperationFunc(painter.WhiteFill)
+	u.PaintBackground(painter.Solid{Color: namedColors["white"]})
+}
+
+func (u *Uistate) PaintBackground(paint painter.Paint) {
+	u.background, u.backgroundClip = paint, u.clip
+	u.backgroundColor = u.clipped(backgroundOperation(paint))
+}
+
+// backgroundOperation повертає операцію заливки фону; для білого, зеленого та чорного кольорів
+// використовуються відповідні готові операції.
+func backgroundOperation(paint painter.Paint) painter.Operation {
+	switch paint {
+	case painter.Solid{Color: namedColors["white"]}:
+		return painter.OperationFunc(painter.WhiteFill)
+	case painter.Solid{Color: namedColors["green"]}:
+		return painter.OperationFunc(painter.GreenFill)
+	case painter.Solid{Color: namedColors["black"]}:
+		return painter.OperationFunc(painter.Reset)
+	}
+	return &painter.PaintFill{Paint: paint}
+}
+
+// SetClip обмежує малювання фону та фігур, доданих після виклику, областю r; nil знімає обмеження.
+func (u *Uistate) SetClip(r painter.Region) {
+	u.clip = r
+}
+
+// SetClipToShape обмежує подальше малювання областю фонового прямокутника, фігури або групи.
+func (u *Uistate) SetClipToShape(target string) error {
+	shape, err := u.shape(target)
+	if err != nil {
+		return err
+	}
+	u.clip = shape
+	return nil
+}
+
+func (u *Uistate) clipped(op painter.Operation) painter.Operation {
+	if u.clip == nil {
+		return op
+	}
+	return &painter.ClipOperation{Region: u.clip, Operation: op}
+}
+
+// SetPaint задає заливку фонового прямокутника (target "bgrect"), фігури за номером або групи за назвою.
+func (u *Uistate) SetPaint(target string, paint painter.Paint) error {
+	shape, err := u.shape(target)
+	if err != nil {
+		return err
+	}
+	shape.SetPaint(paint)
+	return nil
+}
+
+func (u *Uistate) shape(target string) (painter.Shape, error) {
+	if target == "bgrect" {
+		if u.backgroundRectangle == nil {
+			return nil, fmt.Errorf("no background rectangle")
+		}
+		return u.backgroundRectangle, nil
+	}
+	if id, err := strconv.Atoi(target); err == nil {
+		return u.figure(id)
+	}
+	return u.group(target)
 }
 
+// BackgroundRectangle задає фоновий прямокутник, а всередині оголошення групи додає прямокутник до неї.
 func (u *Uistate) BackgroundRectangle(firstPoint image.Point, secondPoint image.Point) {
-	u.backgroundRectangle = &painter.BackgroundRectangle{
+	rect := u.transformRectangle(&painter.BackgroundRectangle{
 		FirstPoint:  firstPoint,
 		SecondPoint: secondPoint,
+		Clip:        u.clip,
+	})
+	if group := u.openGroup(); group != nil {
+		group.Shapes = append(group.Shapes, rect)
+		return
+	}
+	u.backgroundRectangle = rect
+}
+
+// AddFigure додає фігуру до сцени або до групи, оголошення якої зараз відкрите.
+func (u *Uistate) AddFigure(figure *painter.CrossFigure) {
+	figure = u.transformFigure(figure)
+	figure.Clip = u.clip
+	if group := u.openGroup(); group != nil {
+		group.Shapes = append(group.Shapes, figure)
+		return
+	}
+	u.figuresArray = append(u.figuresArray, figure)
+}
+
+// OpenGroup починає оголошення групи: наступні фігури додаються до неї до виклику CloseGroup.
+func (u *Uistate) OpenGroup(name string) error {
+	if err := checkGroupName(name); err != nil {
+		return err
+	}
+	if _, ok := u.groupsByName[name]; ok {
+		return fmt.Errorf("group %s already exists", name)
+	}
+	group := &painter.Group{Name: name, Clip: u.clip}
+	parent := u.openGroup()
+	if parent == nil {
+		parent = &u.groups
+	}
+	parent.Shapes = append(parent.Shapes, group)
+	if u.groupsByName == nil {
+		u.groupsByName = make(map[string]*painter.Group)
+	}
+	u.groupsByName[name] = group
+	u.openGroups = append(u.openGroups, group)
+	return nil
+}
+
+// checkGroupName перевіряє, що за назвою name команди знайдуть саме групу: "bgrect" та числа вони розуміють як
+// фоновий прямокутник і номер фігури.
+func checkGroupName(name string) error {
+	if name == "bgrect" {
+		return fmt.Errorf("group name %s is reserved for the background rectangle", name)
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return fmt.Errorf("group name %s is a figure number", name)
+	}
+	return nil
+}
+
+func (u *Uistate) CloseGroup() error {
+	if len(u.openGroups) == 0 {
+		return fmt.Errorf("closing brace without matching group")
+	}
+	u.openGroups = u.openGroups[:len(u.openGroups)-1]
+	return nil
+}
+
+// CheckGroupsClosed повертає помилку, якщо у скрипті залишилися незакриті оголошення груп.
+func (u *Uistate) CheckGroupsClosed() error {
+	if group := u.openGroup(); group != nil {
+		return fmt.Errorf("group %s is not closed", group.Name)
+	}
+	return nil
+}
+
+func (u *Uistate) AddGroupMoveOperation(name string, x int, y int) error {
+	group, err := u.group(name)
+	if err != nil {
+		return err
+	}
+	u.moveOperations = append(u.moveOperations, &painter.GroupMoveOperation{X: x, Y: y, Group: group})
+	return nil
+}
+
+func (u *Uistate) SetGroupHidden(name string, hidden bool) error {
+	group, err := u.group(name)
+	if err != nil {
+		return err
+	}
+	group.Hidden = hidden
+	return nil
+}
+
+func (u *Uistate) SetGroupColor(name string, c color.Color) error {
+	group, err := u.group(name)
+	if err != nil {
+		return err
+	}
+	group.SetColor(c)
+	return nil
+}
+
+// DeleteGroup видаляє групу разом з усіма її фігурами та вкладеними групами.
+func (u *Uistate) DeleteGroup(name string) error {
+	group, err := u.group(name)
+	if err != nil {
+		return err
+	}
+	for _, open := range u.openGroups {
+		if open == group {
+			return fmt.Errorf("cannot delete group %s inside its declaration", name)
+		}
+	}
+	u.groups.Remove(group)
+	u.forgetGroup(group)
+	return nil
+}
+
+func (u *Uistate) forgetGroup(group *painter.Group) {
+	delete(u.groupsByName, group.Name)
+	for _, s := range group.Shapes {
+		if inner, ok := s.(*painter.Group); ok {
+			u.forgetGroup(inner)
+		}
+	}
+}
+
+func (u *Uistate) group(name string) (*painter.Group, error) {
+	group, ok := u.groupsByName[name]
+	if !ok {
+		return nil, fmt.Errorf("no group named %s", name)
+	}
+	return group, nil
+}
+
+func (u *Uistate) openGroup() *painter.Group {
+	if len(u.openGroups) == 0 {
+		return nil
+	}
+	return u.openGroups[len(u.openGroups)-1]
+}
+
+func (u *Uistate) PushTransform() {
+	u.transforms = append(u.transforms, u.currentTransform())
+}
+
+func (u *Uistate) PopTransform() error {
+	if len(u.transforms) == 0 {
+		return fmt.Errorf("pop without matching push")
+	}
+	u.transform = u.transforms[len(u.transforms)-1]
+	u.transforms = u.transforms[:len(u.transforms)-1]
+	return nil
+}
+
+func (u *Uistate) Translate(x int, y int) {
+	u.transform = u.currentTransform().multiply(translation(float64(x), float64(y)))
+}
+
+func (u *Uistate) Rotate(angle float64) {
+	u.transform = u.currentTransform().multiply(rotation(angle))
+}
+
+func (u *Uistate) Scale(factor float64) {
+	u.transform = u.currentTransform().multiply(scaling(factor))
+}
+
+// currentTransform повертає поточне перетворення; нульове значення Uistate відповідає тотожному перетворенню.
+func (u *Uistate) currentTransform() transform {
+	if u.transform == (transform{}) {
+		return identity
+	}
+	return u.transform
+}
+
+func (u *Uistate) transformFigure(figure *painter.CrossFigure) *painter.CrossFigure {
+	m := u.currentTransform()
+	if m == identity {
+		return figure
+	}
+	figure.CentralPoint = m.applyPoint(figure.CentralPoint)
+	figure.Angle += m.angle()
+	figure.Scale = m.scale() * scaleOrDefault(figure.Scale)
+	return figure
+}
+
+// transformRectangle переносить центр прямокутника, масштабує його розміри та задає кут повороту.
+func (u *Uistate) transformRectangle(rect *painter.BackgroundRectangle) *painter.BackgroundRectangle {
+	m := u.currentTransform()
+	if m == identity {
+		return rect
 	}
+	cx := float64(rect.FirstPoint.X+rect.SecondPoint.X) / 2
+	cy := float64(rect.FirstPoint.Y+rect.SecondPoint.Y) / 2
+	ncx, ncy := m.apply(cx, cy)
+	s := m.scale()
+	corner := func(p image.Point) image.Point {
+		return image.Pt(
+			int(math.Round(ncx+s*(float64(p.X)-cx))),
+			int(math.Round(ncy+s*(float64(p.Y)-cy))),
+		)
+	}
+	rect.FirstPoint, rect.SecondPoint = corner(rect.FirstPoint), corner(rect.SecondPoint)
+	rect.Angle += m.angle()
+	return rect
 }
 
-func (u *Uistate) AddFigure(centralPoint image.Point) {
-	figure := painter.CrossFigure{
-		CentralPoint: centralPoint,
+func scaleOrDefault(scale float64) float64 {
+	if scale == 0 {
+		return 1
 	}
-	u.figuresArray = append(u.figuresArray, &figure)
+	return scale
 }
 
 func (u *Uistate) AddMoveOperation(x int, y int) {
@@ -82,11 +397,47 @@ func (u *Uistate) AddMoveOperation(x int, y int) {
 	u.moveOperations = append(u.moveOperations, &moveOp)
 }
 
+func (u *Uistate) AddRotateOperation(id int, angle float64) error {
+	figure, err := u.figure(id)
+	if err != nil {
+		return err
+	}
+	u.moveOperations = append(u.moveOperations, &painter.RotateOperation{Angle: angle, Figure: figure})
+	return nil
+}
+
+func (u *Uistate) AddScaleOperation(id int, factor float64) error {
+	figure, err := u.figure(id)
+	if err != nil {
+		return err
+	}
+	u.moveOperations = append(u.moveOperations, &painter.ScaleOperation{Factor: factor, Figure: figure})
+	return nil
+}
+
+// figure повертає фігуру за її номером; фігури нумеруються з 1 у порядку додавання.
+func (u *Uistate) figure(id int) (*painter.CrossFigure, error) {
+	if id < 1 || id > len(u.figuresArray) {
+		return nil, fmt.Errorf("no figure with id %d", id)
+	}
+	return u.figuresArray[id-1], nil
+}
+
 func (u *Uistate) ResetStateAndBackground() {
 	u.Reset()
 	u.backgroundColor = painter.OperationFunc(painter.Reset)
 }
 
+// StartRecording починає запис анімації у файл path перед малюванням сцени.
+func (u *Uistate) StartRecording(r *painter.GIFRecorder, path string) {
+	u.recordStart = &painter.RecordOperation{Recorder: r, Path: path}
+}
+
+// StopRecording завершує запис анімації після того, як сцену буде показано.
+func (u *Uistate) StopRecording(r *painter.GIFRecorder) {
+	u.recordStop = &painter.RecordOperation{Recorder: r}
+}
+
 func (u *Uistate) SetUpdateOperation() {
 	u.updateOperation = painter.UpdateOp
 }
@@ -0,0 +1,100 @@
+package lang
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+)
+
+// mjpegQuality якість JPEG кадрів у потоці MJPEG.
+const mjpegQuality = 80
+
+//go:embed viewer.html
+var viewerPage []byte
+
+// ViewerHandler конструює обробник, що повертає сторінку перегляду полотна у браузері: сторінка показує потік
+// /stream.mjpeg і надсилає введені скрипти на HttpHandler.
+func ViewerHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = rw.Write(viewerPage)
+	})
+}
+
+// MJPEGHandler конструює обробник потоку MJPEG: клієнт одразу отримує поточну сцену p, а далі - кожен новий кадр.
+// Кадри, сформовані не у пам'яті, не мають пікселів, тож для них сцена p малюється окремо. Якщо клієнт не встигає,
+// проміжні кадри пропускаються.
+func MJPEGHandler(frames *painter.FrameNotifier, p *Parser) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := rw.(http.Flusher)
+		if !ok {
+			http.Error(rw, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		ch, unsubscribe := frames.SubscribeImages()
+		defer unsubscribe()
+		mw := multipart.NewWriter(rw)
+		rw.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+mw.Boundary())
+		rw.Header().Set("Cache-Control", "no-cache")
+		rw.WriteHeader(http.StatusOK)
+
+		// Пікселі показаних кадрів копіюються лише для підписників, тож перший кадр малюється зі сцени.
+		frame := frames.Last()
+		for {
+			if frame.Image == nil {
+				var err error
+				if frame.Image, err = Render(p.Scene()); err != nil {
+					return
+				}
+			}
+			if err := writeJPEGPart(mw, frame); err != nil {
+				return
+			}
+			flusher.Flush()
+			select {
+			case frame = <-ch:
+			case <-r.Context().Done():
+				return
+			}
+			// Пропускаємо кадри, що накопичилися, поки кодувався попередній.
+			for drained := false; !drained; {
+				select {
+				case frame = <-ch:
+				default:
+					drained = true
+				}
+			}
+		}
+	})
+}
+
+func writeJPEGPart(mw *multipart.Writer, frame painter.Frame) error {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, frame.Image, &jpeg.Options{Quality: mjpegQuality}); err != nil {
+		return err
+	}
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":   {"image/jpeg"},
+		"Content-Length": {fmt.Sprint(buf.Len())},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(part)
+	return err
+}
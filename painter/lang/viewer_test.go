@@ -0,0 +1,61 @@
+package lang
+
+import (
+	"context"
+	"image"
+	"image/jpeg"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMJPEGHandler(t *testing.T) {
+	parser := &Parser{}
+	_, err := parser.Parse(strings.NewReader("green"))
+	require.NoError(t, err)
+	frames := &painter.FrameNotifier{}
+	texture := painter.NewImageTexture(image.Pt(800, 800))
+	painter.GreenFill(texture)
+	// Nobody watches the stream yet, so the frame is not copied.
+	frames.Update(texture)
+	assert.Nil(t, frames.Last().Image)
+
+	server := httptest.NewServer(MJPEGHandler(frames, parser))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/x-mixed-replace", mediaType)
+	parts := multipart.NewReader(resp.Body, params["boundary"])
+
+	// The current scene comes first, then every new frame.
+	part, err := parts.NextPart()
+	require.NoError(t, err)
+	img, err := jpeg.Decode(part)
+	require.NoError(t, err)
+	r, g, b, _ := img.At(400, 400).RGBA()
+	assert.True(t, r < 0x1000 && g > 0xf000 && b < 0x1000)
+
+	painter.WhiteFill(texture)
+	frames.Update(texture)
+	part, err = parts.NextPart()
+	require.NoError(t, err)
+	img, err = jpeg.Decode(part)
+	require.NoError(t, err)
+	r, g, b, _ = img.At(400, 400).RGBA()
+	assert.True(t, r > 0xf000 && g > 0xf000 && b > 0xf000)
+}
@@ -0,0 +1,104 @@
+package lang
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocketMessage повідомлення, яке WebSocketHandler надсилає клієнту у форматі JSON.
+type WebSocketMessage struct {
+	Type  string     `json:"type"`            // ack, error або frame
+	Seq   int        `json:"seq,omitempty"`   // номер скрипту в межах з'єднання для ack та error
+	Error string     `json:"error,omitempty"` // причина, з якої скрипт відхилено
+	Frame int        `json:"frame,omitempty"` // номер показаного кадру
+	Time  *time.Time `json:"time,omitempty"`  // час показу кадру
+}
+
+// WebSocketHandler конструює обробник WebSocket з'єднань, через які клієнт безперервно надсилає рядки скриптів.
+// Кожна команда (оголошення групи - цілком) розбирається тим самим Parser, операції передаються у painter.Loop,
+// а клієнт отримує підтвердження або помилку для кожної команди та повідомлення про кожен показаний кадр.
+// limits (nil вимикає обмеження) обмежує кількість команд у кожному скрипті, частоту скриптів та розмір ще не
+// завершеного скрипту: з'єднання, у якому він перевищує MaxBodySize, закривається.
+func WebSocketHandler(loop *painter.Loop, p *Parser, frames *painter.FrameNotifier, limits *Limits) websocket.Server {
+	return websocket.Server{Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+		granted := Granted(ws.Request())
+		var maxSize int64
+		if limits != nil {
+			maxSize = limits.MaxBodySize
+			ws.MaxPayloadBytes = int(maxSize)
+		}
+
+		if frames != nil {
+			events, unsubscribe := frames.Subscribe()
+			defer unsubscribe()
+			go func() {
+				for frame := range events {
+					t := frame.Time
+					if websocket.JSON.Send(ws, WebSocketMessage{Type: "frame", Frame: frame.Number, Time: &t}) != nil {
+						return
+					}
+				}
+			}()
+		}
+
+		var (
+			script strings.Builder
+			depth  int
+			seq    int
+		)
+		for {
+			var msg string
+			if err := websocket.Message.Receive(ws, &msg); err != nil {
+				return
+			}
+			for _, line := range strings.Split(msg, "\n") {
+				if strings.TrimSpace(line) == "" {
+					continue
+				}
+				script.WriteString(line + "\n")
+				if maxSize > 0 && int64(script.Len()) > maxSize {
+					// Решту оголошення групи вже не можна виконати окремо, тож з'єднання закривається.
+					err := fmt.Errorf("script is larger than %d bytes", maxSize)
+					_ = websocket.JSON.Send(ws, WebSocketMessage{Type: "error", Seq: seq + 1, Error: err.Error()})
+					return
+				}
+				if depth += GroupDepth(line); depth > 0 {
+					continue
+				}
+
+				seq++
+				reply := WebSocketMessage{Type: "ack", Seq: seq}
+				err := limits.checkScript(ws.Request(), script.String())
+				if err == nil {
+					err = runScript(loop, p, script.String(), granted)
+				}
+				if err != nil {
+					reply = WebSocketMessage{Type: "error", Seq: seq, Error: err.Error()}
+				}
+				script.Reset()
+				depth = 0
+				if err := websocket.JSON.Send(ws, reply); err != nil {
+					return
+				}
+			}
+		}
+	}}
+}
+
+// runScript виконує скрипт, якщо рівень доступу granted це дозволяє, і передає отримані операції у цикл подій.
+func runScript(loop *painter.Loop, p *Parser, script string, granted Permission) error {
+	if scriptPermission(script) > granted {
+		return fmt.Errorf("token is not allowed to run this script")
+	}
+	ops, err := p.Parse(strings.NewReader(script))
+	if err != nil {
+		return err
+	}
+	loop.Post(painter.OperationList(ops))
+	return nil
+}
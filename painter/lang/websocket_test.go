@@ -0,0 +1,78 @@
+package lang
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestWebSocketHandler(t *testing.T) {
+	var loop painter.Loop
+	parser := &Parser{}
+	frames := &painter.FrameNotifier{}
+	server := httptest.NewServer(WebSocketHandler(&loop, parser, frames, nil))
+	defer server.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+	receive := func() WebSocketMessage {
+		var msg WebSocketMessage
+		require.NoError(t, websocket.JSON.Receive(ws, &msg))
+		return msg
+	}
+
+	// A group declaration split across messages is parsed as one script.
+	require.NoError(t, websocket.Message.Send(ws, "white\ngroup g {"))
+	assert.Equal(t, WebSocketMessage{Type: "ack", Seq: 1}, receive())
+	require.NoError(t, websocket.Message.Send(ws, "figure 0.5 0.5\n}\nbad"))
+	assert.Equal(t, WebSocketMessage{Type: "ack", Seq: 2}, receive())
+	assert.Equal(t, WebSocketMessage{Type: "error", Seq: 3, Error: "line 1: invalid command bad"}, receive())
+	assert.Len(t, parser.Scene().Groups, 1)
+	assert.Len(t, loop.Mq.Ops, 2)
+
+	frames.Update(nil)
+	msg := receive()
+	assert.Equal(t, "frame", msg.Type)
+	assert.Equal(t, 1, msg.Frame)
+}
+
+func TestWebSocketHandler_Limits(t *testing.T) {
+	var loop painter.Loop
+	limits := &Limits{MaxBodySize: 64, MaxCommands: 2, Rate: 1, Burst: 2, Now: func() time.Time { return time.Time{} }}
+	server := httptest.NewServer(WebSocketHandler(&loop, &Parser{}, nil, limits))
+	defer server.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+	receive := func() WebSocketMessage {
+		var msg WebSocketMessage
+		require.NoError(t, websocket.JSON.Receive(ws, &msg))
+		return msg
+	}
+
+	require.NoError(t, websocket.Message.Send(ws, "group g {\nwhite\ngreen\n}"))
+	assert.Equal(t, "script has 4 commands, at most 2 are allowed", receive().Error)
+	require.NoError(t, websocket.Message.Send(ws, "white\ngreen"))
+	assert.Equal(t, WebSocketMessage{Type: "ack", Seq: 2}, receive())
+	assert.Equal(t, WebSocketMessage{Type: "ack", Seq: 3}, receive())
+	// The clock stands still, so the bucket does not refill after the burst.
+	require.NoError(t, websocket.Message.Send(ws, "white"))
+	assert.Equal(t, "too many requests, retry in 1s", receive().Error)
+
+	// An unclosed group cannot grow past the size limit.
+	require.NoError(t, websocket.Message.Send(ws, "group h {"))
+	for i := 0; i < 10; i++ {
+		if websocket.Message.Send(ws, "figure 0.5 0.5") != nil {
+			break
+		}
+	}
+	assert.Equal(t, "script is larger than 64 bytes", receive().Error)
+}
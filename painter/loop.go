@@ -24,7 +24,10 @@ type Loop struct {
 	stopReq bool
 }
 
-var size = image.Pt(800, 800)
+// CanvasSize розмір сторони квадратної текстури, у пікселях якої описано сцену.
+const CanvasSize = 800
+
+var size = image.Pt(CanvasSize, CanvasSize)
 
 // Start запускає цикл подій. Цей метод потрібно запустити до того, як викликати на ньому будь-які інші методи.
 func (l *Loop) Start(s screen.Screen) {
@@ -0,0 +1,110 @@
+package painter
+
+import (
+	"image"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// frameBuffer кількість повідомлень про кадри, які може накопичити підписник; решта відкидається, щоб повільний
+// підписник не гальмував цикл подій.
+const frameBuffer = 16
+
+// Frame повідомлення про показаний кадр.
+type Frame struct {
+	Number  int       // номер кадру від запуску, починаючи з 1
+	Time    time.Time // час показу
+	Version int       // версія сцени, яку показує кадр, з останньої виконаної операції SceneVersion
+	// Image спільна для всіх підписників копія кадру, яку не можна змінювати. Копія робиться лише тоді, коли є
+	// підписники SubscribeImages, тож інакше, як і для кадрів, сформованих не у пам'яті, Image дорівнює nil.
+	Image *image.RGBA
+}
+
+// FrameNotifier передає текстури іншому Receiver та повідомляє підписників про кожен показаний кадр.
+type FrameNotifier struct {
+	Receiver Receiver
+
+	mu          sync.Mutex
+	last        Frame
+	version     int                 // версія сцени з останньої виконаної операції SceneVersion
+	subscribers map[chan Frame]bool // чи потрібні підписнику пікселі кадрів
+	images      int                 // кількість підписників, яким потрібні пікселі
+}
+
+func (n *FrameNotifier) Update(t screen.Texture) {
+	n.mu.Lock()
+	var img *image.RGBA
+	if it, ok := t.(*ImageTexture); ok && n.images != 0 {
+		img = it.Snapshot()
+	}
+	frame := Frame{Number: n.last.Number + 1, Time: time.Now(), Version: n.version, Image: img}
+	n.last = frame
+	for ch := range n.subscribers {
+		select {
+		case ch <- frame:
+		default:
+		}
+	}
+	n.mu.Unlock()
+
+	if n.Receiver != nil {
+		n.Receiver.Update(t)
+	}
+}
+
+// SceneVersion повертає операцію, після виконання якої показані кадри позначаються версією сцени version. Операція
+// виконується у циклі подій разом з операціями, що малюють цю версію, тож кадр отримує версію саме тієї сцени,
+// яку показує.
+func (n *FrameNotifier) SceneVersion(version int) Operation {
+	return OperationFunc(func(screen.Texture) {
+		n.mu.Lock()
+		n.version = version
+		n.mu.Unlock()
+	})
+}
+
+// Last повертає останній показаний кадр; номер нульового значення дорівнює 0.
+func (n *FrameNotifier) Last() Frame {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.last
+}
+
+// Subscribe повертає канал повідомлень про нові кадри та функцію, що скасовує підписку і закриває канал.
+func (n *FrameNotifier) Subscribe() (<-chan Frame, func()) {
+	return n.subscribe(false)
+}
+
+// SubscribeImages працює як Subscribe, але поки підписка діє, кожен кадр, сформований у пам'яті, приходить з копією
+// пікселів у полі Image.
+func (n *FrameNotifier) SubscribeImages() (<-chan Frame, func()) {
+	return n.subscribe(true)
+}
+
+func (n *FrameNotifier) subscribe(images bool) (<-chan Frame, func()) {
+	ch := make(chan Frame, frameBuffer)
+	n.mu.Lock()
+	if n.subscribers == nil {
+		n.subscribers = make(map[chan Frame]bool)
+	}
+	n.subscribers[ch] = images
+	if images {
+		n.images++
+	}
+	n.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			n.mu.Lock()
+			delete(n.subscribers, ch)
+			if images {
+				n.images--
+			}
+			n.mu.Unlock()
+			close(ch)
+		})
+	}
+}
@@ -4,8 +4,6 @@ import (
 	"image"
 	"image/color"
 
-	"golang.org/x/image/draw"
-
 	"golang.org/x/exp/shiny/screen"
 )
 
@@ -54,27 +52,147 @@ func GreenFill(t screen.Texture) {
 type BackgroundRectangle struct {
 	FirstPoint  image.Point
 	SecondPoint image.Point
+	Angle       float64     // кут повороту навколо центру у градусах за годинниковою стрілкою
+	Color       color.Color // колір прямокутника; nil означає чорний
+	Paint       Paint       // заливка, що замінює Color
+	Clip        Region      // область, якою обмежене малювання; nil означає всю текстуру
 }
 
 // Do малює наш прямокутник
 
 func (op *BackgroundRectangle) Do(t screen.Texture) bool {
-	c := color.Black
-	t.Fill(image.Rect(op.FirstPoint.X, op.FirstPoint.Y, op.SecondPoint.X, op.SecondPoint.Y), c, screen.Src)
+	var c color.Color = color.Black
+	if op.Color != nil {
+		c = op.Color
+	}
+	t = clip(t, op.Clip)
+	if op.Angle == 0 {
+		fillArea(t, op.rect(), c, op.Paint)
+		return false
+	}
+	fillPolygon(t, op.polygon(), c, op.Paint)
 	return false
 }
 
+func (op *BackgroundRectangle) Bounds() image.Rectangle {
+	if op.Angle == 0 {
+		return op.rect()
+	}
+	return polygonBounds(op.polygon())
+}
+
+func (op *BackgroundRectangle) Contains(p image.Point) bool {
+	if op.Angle == 0 {
+		return p.In(op.rect())
+	}
+	return polygonContains(op.polygon(), p)
+}
+
+func (op *BackgroundRectangle) rect() image.Rectangle {
+	return image.Rect(op.FirstPoint.X, op.FirstPoint.Y, op.SecondPoint.X, op.SecondPoint.Y)
+}
+
+// polygon повертає вершини прямокутника, повернутого на Angle градусів навколо центру.
+func (op *BackgroundRectangle) polygon() []point {
+	r := op.rect()
+	center := point{float64(r.Min.X+r.Max.X) / 2, float64(r.Min.Y+r.Max.Y) / 2}
+	return rotatedRect(center, float64(r.Dx()), float64(r.Dy()), op.Angle)
+}
+
+func (op *BackgroundRectangle) Translate(dx, dy int) {
+	d := image.Pt(dx, dy)
+	op.FirstPoint, op.SecondPoint = op.FirstPoint.Add(d), op.SecondPoint.Add(d)
+	if op.Paint != nil {
+		op.Paint = op.Paint.Translate(dx, dy)
+	}
+}
+
+func (op *BackgroundRectangle) SetColor(c color.Color) {
+	op.Color, op.Paint = c, nil
+}
+
+func (op *BackgroundRectangle) SetPaint(p Paint) {
+	op.Paint = p
+}
+
+// Типова геометрія хреста у пікселях.
+const (
+	DefaultArmLength = 400
+	DefaultArmWidth  = 160
+)
+
+// CrossFigure жовтий хрест з центром у CentralPoint. Нульові ArmLength, ArmWidth та Scale означають типову
+// геометрію: плечі 400x160 без масштабування.
 type CrossFigure struct {
 	CentralPoint image.Point
+	ArmLength    int         // довжина плеча у пікселях
+	ArmWidth     int         // ширина плеча у пікселях
+	Angle        float64     // кут повороту у градусах за годинниковою стрілкою
+	Scale        float64     // рівномірний масштаб
+	Color        color.Color // колір хреста; nil означає жовтий
+	Paint        Paint       // заливка, що замінює Color
+	Clip         Region      // область, якою обмежене малювання; nil означає всю текстуру
 }
 
 func (op *CrossFigure) Do(t screen.Texture) bool {
-	c := color.RGBA{R: 255, G: 255, B: 0, A: 1}
-	t.Fill(image.Rect(op.CentralPoint.X-200, op.CentralPoint.Y+80, op.CentralPoint.X+200, op.CentralPoint.Y-80), c, draw.Src)
-	t.Fill(image.Rect(op.CentralPoint.X-80, op.CentralPoint.Y+200, op.CentralPoint.X+80, op.CentralPoint.Y-200), c, draw.Src)
+	var c color.Color = color.RGBA{R: 255, G: 255, B: 0, A: 1}
+	if op.Color != nil {
+		c = op.Color
+	}
+	t = clip(t, op.Clip)
+	length, width := op.armSize()
+	fillRotatedRect(t, op.CentralPoint, length, width, op.Angle, c, op.Paint)
+	fillRotatedRect(t, op.CentralPoint, width, length, op.Angle, c, op.Paint)
 	return false
 }
 
+func (op *CrossFigure) Bounds() image.Rectangle {
+	length, width := op.armSize()
+	return rotatedRectBounds(op.CentralPoint, length, width, op.Angle).
+		Union(rotatedRectBounds(op.CentralPoint, width, length, op.Angle))
+}
+
+func (op *CrossFigure) Contains(p image.Point) bool {
+	length, width := op.armSize()
+	return rotatedRectContains(op.CentralPoint, length, width, op.Angle, p) ||
+		rotatedRectContains(op.CentralPoint, width, length, op.Angle, p)
+}
+
+func (op *CrossFigure) Translate(dx, dy int) {
+	op.CentralPoint = op.CentralPoint.Add(image.Pt(dx, dy))
+	if op.Paint != nil {
+		op.Paint = op.Paint.Translate(dx, dy)
+	}
+}
+
+func (op *CrossFigure) SetColor(c color.Color) {
+	op.Color, op.Paint = c, nil
+}
+
+func (op *CrossFigure) SetPaint(p Paint) {
+	op.Paint = p
+}
+
+// armSize повертає довжину та ширину плеча з урахуванням масштабу.
+func (op *CrossFigure) armSize() (length, width float64) {
+	length, width = DefaultArmLength, DefaultArmWidth
+	if op.ArmLength != 0 {
+		length = float64(op.ArmLength)
+	}
+	if op.ArmWidth != 0 {
+		width = float64(op.ArmWidth)
+	}
+	scale := op.scale()
+	return length * scale, width * scale
+}
+
+func (op *CrossFigure) scale() float64 {
+	if op.Scale == 0 {
+		return 1
+	}
+	return op.Scale
+}
+
 type MoveOperation struct {
 	X            int
 	Y            int
@@ -89,6 +207,28 @@ func (op *MoveOperation) Do(t screen.Texture) bool {
 	return false
 }
 
+// RotateOperation повертає хрест Figure на Angle градусів.
+type RotateOperation struct {
+	Angle  float64
+	Figure *CrossFigure
+}
+
+func (op *RotateOperation) Do(t screen.Texture) bool {
+	op.Figure.Angle += op.Angle
+	return false
+}
+
+// ScaleOperation змінює масштаб хреста Figure у Factor разів.
+type ScaleOperation struct {
+	Factor float64
+	Figure *CrossFigure
+}
+
+func (op *ScaleOperation) Do(t screen.Texture) bool {
+	op.Figure.Scale = op.Figure.scale() * op.Factor
+	return false
+}
+
 func Reset(t screen.Texture) {
 	t.Fill(t.Bounds(), color.Black, screen.Src)
 }
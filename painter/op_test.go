@@ -0,0 +1,70 @@
+package painter
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/exp/shiny/screen"
+)
+
+func TestCrossFigure_Default(t *testing.T) {
+	textureMock := new(MockTexture)
+	textureMock.On("Fill", mock.Anything, mock.Anything, screen.Src).Return()
+
+	figure := &CrossFigure{CentralPoint: image.Pt(400, 400)}
+	figure.Do(textureMock)
+
+	c := color.RGBA{R: 255, G: 255, B: 0, A: 1}
+	textureMock.AssertCalled(t, "Fill", image.Rect(200, 320, 600, 480), c, screen.Src)
+	textureMock.AssertCalled(t, "Fill", image.Rect(320, 200, 480, 600), c, screen.Src)
+	textureMock.AssertNumberOfCalls(t, "Fill", 2)
+}
+
+func TestCrossFigure_Scaled(t *testing.T) {
+	textureMock := new(MockTexture)
+	textureMock.On("Fill", mock.Anything, mock.Anything, screen.Src).Return()
+
+	figure := &CrossFigure{CentralPoint: image.Pt(400, 400), ArmLength: 200, ArmWidth: 100}
+	(&ScaleOperation{Factor: 0.5, Figure: figure}).Do(textureMock)
+	figure.Do(textureMock)
+
+	c := color.RGBA{R: 255, G: 255, B: 0, A: 1}
+	textureMock.AssertCalled(t, "Fill", image.Rect(350, 375, 450, 425), c, screen.Src)
+	textureMock.AssertCalled(t, "Fill", image.Rect(375, 350, 425, 450), c, screen.Src)
+}
+
+func TestCrossFigure_Rotated(t *testing.T) {
+	textureMock := new(MockTexture)
+	textureMock.On("Fill", mock.Anything, mock.Anything, screen.Src).Return()
+
+	figure := &CrossFigure{CentralPoint: image.Pt(400, 400)}
+	(&RotateOperation{Angle: 90, Figure: figure}).Do(textureMock)
+	figure.Do(textureMock)
+
+	// A cross rotated by 90 degrees covers the same rows as the original one, but is filled row by row.
+	c := color.RGBA{R: 255, G: 255, B: 0, A: 1}
+	textureMock.AssertCalled(t, "Fill", image.Rect(320, 200, 480, 201), c, screen.Src)
+	textureMock.AssertCalled(t, "Fill", image.Rect(200, 400, 600, 401), c, screen.Src)
+	textureMock.AssertNumberOfCalls(t, "Fill", 400+160)
+}
+
+func TestGroup(t *testing.T) {
+	textureMock := new(MockTexture)
+	textureMock.On("Fill", mock.Anything, mock.Anything, screen.Src).Return()
+
+	rect := &BackgroundRectangle{FirstPoint: image.Pt(0, 0), SecondPoint: image.Pt(100, 100)}
+	group := &Group{Name: "outer", Shapes: []Shape{rect, &Group{Name: "inner", Shapes: []Shape{&CrossFigure{}}}}}
+
+	(&GroupMoveOperation{X: 10, Y: 20, Group: group}).Do(textureMock)
+	group.SetColor(color.White)
+	group.Do(textureMock)
+
+	textureMock.AssertCalled(t, "Fill", image.Rect(10, 20, 110, 120), color.White, screen.Src)
+	textureMock.AssertNumberOfCalls(t, "Fill", 3)
+
+	group.Hidden = true
+	group.Do(textureMock)
+	textureMock.AssertNumberOfCalls(t, "Fill", 3)
+}
@@ -0,0 +1,244 @@
+package painter
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// Paint визначає колір кожної точки області, що зафарбовується. Координати задаються у пікселях текстури.
+type Paint interface {
+	At(x, y int) color.Color
+	// Translate повертає заливку, зсунуту на dx, dy разом з фігурою, до якої вона застосована.
+	Translate(dx, dy int) Paint
+}
+
+// Solid однотонна заливка кольором Color.
+type Solid struct {
+	Color color.Color
+}
+
+func (s Solid) At(x, y int) color.Color { return s.Color }
+
+func (s Solid) Translate(dx, dy int) Paint { return s }
+
+// gradientSteps кількість відтінків градієнта; обмеження зменшує кількість викликів Fill.
+const gradientSteps = 64
+
+// LinearGradient лінійний градієнт від From кольору FromColor до To кольору ToColor.
+type LinearGradient struct {
+	From, To           image.Point
+	FromColor, ToColor color.Color
+}
+
+func (g LinearGradient) At(x, y int) color.Color {
+	dx, dy := float64(g.To.X-g.From.X), float64(g.To.Y-g.From.Y)
+	length := dx*dx + dy*dy
+	if length == 0 {
+		return g.FromColor
+	}
+	pos := (float64(x-g.From.X)*dx + float64(y-g.From.Y)*dy) / length
+	return mix(g.FromColor, g.ToColor, pos)
+}
+
+func (g LinearGradient) Translate(dx, dy int) Paint {
+	d := image.Pt(dx, dy)
+	g.From, g.To = g.From.Add(d), g.To.Add(d)
+	return g
+}
+
+// RadialGradient радіальний градієнт від кольору Inner у центрі Center до кольору Outer на відстані Radius.
+type RadialGradient struct {
+	Center       image.Point
+	Radius       int
+	Inner, Outer color.Color
+}
+
+func (g RadialGradient) At(x, y int) color.Color {
+	if g.Radius <= 0 {
+		return g.Outer
+	}
+	d := math.Hypot(float64(x-g.Center.X), float64(y-g.Center.Y))
+	return mix(g.Inner, g.Outer, d/float64(g.Radius))
+}
+
+func (g RadialGradient) Translate(dx, dy int) Paint {
+	g.Center = g.Center.Add(image.Pt(dx, dy))
+	return g
+}
+
+// Checkerboard шахівниця з квадратних клітинок розміру Size, відлік яких починається від Origin.
+type Checkerboard struct {
+	Origin image.Point
+	Size   int
+	A, B   color.Color
+}
+
+func (c Checkerboard) At(x, y int) color.Color {
+	if c.Size <= 0 {
+		return c.A
+	}
+	cx := floorDiv(x-c.Origin.X, c.Size)
+	cy := floorDiv(y-c.Origin.Y, c.Size)
+	if (cx+cy)%2 == 0 {
+		return c.A
+	}
+	return c.B
+}
+
+func (c Checkerboard) Translate(dx, dy int) Paint {
+	c.Origin = c.Origin.Add(image.Pt(dx, dy))
+	return c
+}
+
+// Stripes смуги однакової ширини Width кольорів A та B, нахилені на Angle градусів.
+type Stripes struct {
+	Origin image.Point
+	Width  int
+	Angle  float64
+	A, B   color.Color
+}
+
+func (s Stripes) At(x, y int) color.Color {
+	if s.Width <= 0 {
+		return s.A
+	}
+	if floorDiv(int(math.Floor(stripeOffset(s.Origin, s.Angle, x, y))), s.Width)%2 == 0 {
+		return s.A
+	}
+	return s.B
+}
+
+func (s Stripes) Translate(dx, dy int) Paint {
+	s.Origin = s.Origin.Add(image.Pt(dx, dy))
+	return s
+}
+
+// Hatching штриховка: лінії кольору Line товщиною LineWidth через кожні Spacing пікселів на фоні Background.
+type Hatching struct {
+	Origin           image.Point
+	Spacing          int
+	LineWidth        int
+	Angle            float64
+	Line, Background color.Color
+}
+
+func (h Hatching) At(x, y int) color.Color {
+	if h.Spacing <= 0 {
+		return h.Background
+	}
+	offset := int(math.Floor(stripeOffset(h.Origin, h.Angle, x, y)))
+	if offset-floorDiv(offset, h.Spacing)*h.Spacing < h.LineWidth {
+		return h.Line
+	}
+	return h.Background
+}
+
+func (h Hatching) Translate(dx, dy int) Paint {
+	h.Origin = h.Origin.Add(image.Pt(dx, dy))
+	return h
+}
+
+// stripeOffset повертає відстань від точки до лінії, що проходить через origin під кутом angle.
+func stripeOffset(origin image.Point, angle float64, x, y int) float64 {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	return float64(x-origin.X)*-sin + float64(y-origin.Y)*cos
+}
+
+// mix повертає колір між a та b у позиції pos з діапазону [0, 1], округлений до одного з gradientSteps відтінків.
+func mix(a, b color.Color, pos float64) color.Color {
+	pos = math.Round(math.Max(0, math.Min(1, pos))*gradientSteps) / gradientSteps
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	lerp := func(x, y uint32) uint16 {
+		return uint16(float64(x) + (float64(y)-float64(x))*pos)
+	}
+	return color.RGBA64{R: lerp(ar, br), G: lerp(ag, bg), B: lerp(ab, bb), A: lerp(aa, ba)}
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// fillArea зафарбовує прямокутник r однотонним кольором c або, якщо задано, заливкою p.
+func fillArea(t screen.Texture, r image.Rectangle, c color.Color, p Paint) {
+	if solid, ok := p.(Solid); ok {
+		c, p = solid.Color, nil
+	}
+	if p == nil {
+		t.Fill(r, c, screen.Src)
+		return
+	}
+	paintRect(t, r, p, screen.Src)
+}
+
+type run struct {
+	x0, x1 int
+	c      color.Color
+}
+
+// paintRect зафарбовує прямокутник заливкою p. Сусідні пікселі однакового кольору об'єднуються у відрізки,
+// а однакові сусідні рядки — у прямокутники, щоб зменшити кількість викликів Fill. Пікселі, для яких заливка
+// повертає nil, залишаються без змін.
+func paintRect(t screen.Texture, r image.Rectangle, p Paint, op draw.Op) {
+	var prev []run
+	start := r.Min.Y
+	flush := func(end int) {
+		for _, rn := range prev {
+			t.Fill(image.Rect(rn.x0, start, rn.x1, end), rn.c, op)
+		}
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		runs := rowRuns(p, y, r.Min.X, r.Max.X)
+		if !equalRuns(runs, prev) {
+			flush(y)
+			prev, start = runs, y
+		}
+	}
+	flush(r.Max.Y)
+}
+
+func rowRuns(p Paint, y, x0, x1 int) []run {
+	var runs []run
+	for x := x0; x < x1; x++ {
+		c := p.At(x, y)
+		if c == nil {
+			continue
+		}
+		if n := len(runs); n > 0 && runs[n-1].c == c && runs[n-1].x1 == x {
+			runs[n-1].x1 = x + 1
+			continue
+		}
+		runs = append(runs, run{x0: x, x1: x + 1, c: c})
+	}
+	return runs
+}
+
+func equalRuns(a, b []run) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// PaintFill зафарбовує всю текстуру заливкою Paint.
+type PaintFill struct {
+	Paint Paint
+}
+
+func (op *PaintFill) Do(t screen.Texture) bool {
+	fillArea(t, t.Bounds(), nil, op.Paint)
+	return false
+}
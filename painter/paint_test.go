@@ -0,0 +1,60 @@
+package painter
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/exp/shiny/screen"
+)
+
+func TestPaints_At(t *testing.T) {
+	checker := Checkerboard{Size: 10, A: color.White, B: color.Black}
+	assert.Equal(t, color.White, checker.At(5, 5))
+	assert.Equal(t, color.Black, checker.At(15, 5))
+	assert.Equal(t, color.Black, checker.At(-5, 5))
+	assert.Equal(t, color.White, checker.Translate(10, 0).At(15, 5))
+
+	stripes := Stripes{Width: 10, A: color.White, B: color.Black}
+	assert.Equal(t, color.White, stripes.At(100, 5))
+	assert.Equal(t, color.Black, stripes.At(100, 15))
+
+	hatch := Hatching{Spacing: 10, LineWidth: 2, Line: color.Black, Background: color.White}
+	assert.Equal(t, color.Black, hatch.At(0, 21))
+	assert.Equal(t, color.White, hatch.At(0, 25))
+
+	linear := LinearGradient{From: image.Pt(0, 0), To: image.Pt(100, 0), FromColor: color.Black, ToColor: color.White}
+	assert.Equal(t, color.RGBA64{A: 0xffff}, linear.At(-10, 0))
+	assert.Equal(t, color.RGBA64{R: 0x7fff, G: 0x7fff, B: 0x7fff, A: 0xffff}, linear.At(50, 30))
+	assert.Equal(t, color.RGBA64{R: 0xffff, G: 0xffff, B: 0xffff, A: 0xffff}, linear.At(150, 0))
+
+	radial := RadialGradient{Center: image.Pt(0, 0), Radius: 10, Inner: color.White, Outer: color.Black}
+	assert.Equal(t, color.RGBA64{A: 0xffff}, radial.At(0, 10))
+}
+
+func TestPaintFill_MergesRuns(t *testing.T) {
+	textureMock := new(MockTexture)
+	textureMock.On("Bounds").Return(image.Rect(0, 0, 4, 4))
+	textureMock.On("Fill", mock.Anything, mock.Anything, screen.Src).Return()
+
+	(&PaintFill{Paint: Checkerboard{Size: 2, A: color.White, B: color.Black}}).Do(textureMock)
+
+	textureMock.AssertCalled(t, "Fill", image.Rect(0, 0, 2, 2), color.White, screen.Src)
+	textureMock.AssertCalled(t, "Fill", image.Rect(2, 0, 4, 2), color.Black, screen.Src)
+	textureMock.AssertCalled(t, "Fill", image.Rect(0, 2, 2, 4), color.Black, screen.Src)
+	textureMock.AssertCalled(t, "Fill", image.Rect(2, 2, 4, 4), color.White, screen.Src)
+	textureMock.AssertNumberOfCalls(t, "Fill", 4)
+}
+
+func TestPaintFill_Solid(t *testing.T) {
+	textureMock := new(MockTexture)
+	textureMock.On("Bounds").Return(image.Rect(0, 0, 800, 800))
+	textureMock.On("Fill", mock.Anything, mock.Anything, screen.Src).Return()
+
+	(&PaintFill{Paint: Solid{Color: color.White}}).Do(textureMock)
+
+	textureMock.AssertCalled(t, "Fill", image.Rect(0, 0, 800, 800), color.White, screen.Src)
+	textureMock.AssertNumberOfCalls(t, "Fill", 1)
+}
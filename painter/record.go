@@ -0,0 +1,171 @@
+package painter
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/shiny/screen"
+)
+
+// GIFRecorder передає текстури іншому Receiver, а між викликами Start та Stop записує показані кадри як анімований
+// GIF. Затримка кожного кадру дорівнює часу до показу наступного. Записуються лише кадри, сформовані у пам'яті
+// (ImageTexture), тому цикл подій потрібно запускати з ImageScreen. Кадри зберігаються у пам'яті до Stop, тож їх
+// кількість обмежена MaxFrames.
+type GIFRecorder struct {
+	Receiver Receiver
+	// Now повертає поточний час; nil означає time.Now.
+	Now func() time.Time
+	// MaxFrames найбільша кількість кадрів одного запису; 0 означає DefaultMaxFrames. Пізніші кадри не записуються.
+	MaxFrames int
+
+	mu     sync.Mutex
+	path   string
+	frames []*image.Paletted
+	times  []time.Time
+	full   bool // запис досяг MaxFrames
+}
+
+// DefaultMaxFrames типова найбільша кількість кадрів одного запису: кадр 800x800 займає 640 КіБ, тож запис тримає у
+// пам'яті не більше ~320 МіБ.
+const DefaultMaxFrames = 500
+
+func (r *GIFRecorder) Update(t screen.Texture) {
+	r.mu.Lock()
+	if r.path != "" {
+		if it, ok := t.(*ImageTexture); ok {
+			if len(r.frames) < r.maxFrames() {
+				r.frames = append(r.frames, paletted(it.Snapshot()))
+				r.times = append(r.times, r.now())
+			} else if !r.full {
+				r.full = true
+				log.Printf("Recording to %s reached %d frames, later frames are not recorded", r.path, len(r.frames))
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	if r.Receiver != nil {
+		r.Receiver.Update(t)
+	}
+}
+
+// Start починає запис кадрів у файл path.
+func (r *GIFRecorder) Start(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.path != "" {
+		return errors.New("recording is already in progress")
+	}
+	if path == "" {
+		return errors.New("no file to record to")
+	}
+	r.path = path
+	return nil
+}
+
+// Stop завершує запис і зберігає записані кадри у файл.
+func (r *GIFRecorder) Stop() error {
+	r.mu.Lock()
+	path, frames, times := r.path, r.frames, r.times
+	r.path, r.frames, r.times, r.full = "", nil, nil, false
+	stopped := r.now()
+	r.mu.Unlock()
+
+	if path == "" {
+		return errors.New("recording is not in progress")
+	}
+	if len(frames) == 0 {
+		return errors.New("no frames were recorded")
+	}
+
+	anim := &gif.GIF{Image: frames}
+	for i := range frames {
+		next := stopped
+		if i+1 < len(times) {
+			next = times[i+1]
+		}
+		anim.Delay = append(anim.Delay, gifDelay(next.Sub(times[i])))
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(f, anim); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (r *GIFRecorder) maxFrames() int {
+	if r.MaxFrames > 0 {
+		return r.MaxFrames
+	}
+	return DefaultMaxFrames
+}
+
+func (r *GIFRecorder) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// gifMinDelay найменша затримка кадру у сотих секунди; меншу більшість переглядачів не відтворює.
+const gifMinDelay = 2
+
+func gifDelay(d time.Duration) int {
+	delay := int((d + 5*time.Millisecond) / (10 * time.Millisecond))
+	if delay < gifMinDelay {
+		return gifMinDelay
+	}
+	return delay
+}
+
+// paletted переводить кадр у палітру Plan9. Кадри зазвичай складаються з кількох кольорів, тож індекси кешуються.
+func paletted(img *image.RGBA) *image.Paletted {
+	p := image.NewPaletted(img.Rect, palette.Plan9)
+	pal := color.Palette(palette.Plan9)
+	cache := make(map[color.RGBA]uint8)
+	for i, j := 0, 0; i < len(img.Pix); i, j = i+4, j+1 {
+		c := color.RGBA{R: img.Pix[i], G: img.Pix[i+1], B: img.Pix[i+2], A: img.Pix[i+3]}
+		index, ok := cache[c]
+		if !ok {
+			index = uint8(pal.Index(c))
+			cache[c] = index
+		}
+		p.Pix[j] = index
+	}
+	return p
+}
+
+// RecordOperation починає (Path не порожній) або завершує запис анімації у циклі подій, тож запис охоплює саме ті
+// кадри, що були показані між операціями. Результат передається у Done, а якщо його не задано, помилка логується.
+type RecordOperation struct {
+	Recorder *GIFRecorder
+	Path     string
+	Done     chan<- error
+}
+
+func (op *RecordOperation) Do(t screen.Texture) bool {
+	var err error
+	if op.Path != "" {
+		err = op.Recorder.Start(op.Path)
+	} else {
+		err = op.Recorder.Stop()
+	}
+	if op.Done != nil {
+		op.Done <- err
+	} else if err != nil {
+		log.Printf("Recording failed: %s", err)
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package painter
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/shiny/screen"
+)
+
+func TestGIFRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.gif")
+	now := time.Unix(0, 0)
+	receiver := new(MockReceiver)
+	receiver.On("Update", mock.Anything).Return()
+	recorder := &GIFRecorder{Receiver: receiver, Now: func() time.Time { return now }}
+
+	var l Loop
+	l.Receiver = recorder
+	l.Start(ImageScreen{})
+	done := make(chan error, 2)
+	step := func(d time.Duration) OperationFunc {
+		return func(screen.Texture) { now = now.Add(d) }
+	}
+
+	l.Post(OperationFunc(WhiteFill))
+	l.Post(UpdateOp) // Not recorded yet.
+	l.Post(&RecordOperation{Recorder: recorder, Path: path, Done: done})
+	l.Post(OperationFunc(GreenFill))
+	l.Post(UpdateOp)
+	l.Post(step(500 * time.Millisecond))
+	l.Post(OperationFunc(GreenFill))
+	l.Post(&BackgroundRectangle{FirstPoint: image.Pt(0, 0), SecondPoint: image.Pt(100, 100)})
+	l.Post(UpdateOp)
+	l.Post(step(time.Second))
+	l.Post(&RecordOperation{Recorder: recorder, Done: done})
+	l.StopAndWait()
+	require.NoError(t, <-done)
+	require.NoError(t, <-done)
+	receiver.AssertNumberOfCalls(t, "Update", 3)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	anim, err := gif.DecodeAll(f)
+	require.NoError(t, err)
+	require.Len(t, anim.Image, 2)
+	assert.Equal(t, []int{50, 100}, anim.Delay)
+	assertRGBA(t, color.RGBA{G: 0xff, A: 0xff}, anim.Image[0].At(50, 50))
+	assertRGBA(t, color.RGBA{A: 0xff}, anim.Image[1].At(50, 50))
+	assertRGBA(t, color.RGBA{G: 0xff, A: 0xff}, anim.Image[1].At(150, 150))
+
+	assert.Error(t, recorder.Stop())
+}
+
+func TestGIFRecorder_MaxFrames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.gif")
+	recorder := &GIFRecorder{MaxFrames: 2}
+	require.NoError(t, recorder.Start(path))
+	texture := NewImageTexture(size)
+	for i := 0; i < 3; i++ {
+		recorder.Update(texture)
+	}
+	require.NoError(t, recorder.Stop())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	anim, err := gif.DecodeAll(f)
+	require.NoError(t, err)
+	assert.Len(t, anim.Image, 2)
+}
+
+func assertRGBA(t *testing.T, expected color.RGBA, c color.Color) {
+	assert.Equal(t, expected, color.RGBAModel.Convert(c))
+}
@@ -5,6 +5,7 @@ import (
 	"image/color"
 	"log"
 
+	"github.com/NikitaSutulov/software-architecture-lab3/painter"
 	"golang.org/x/exp/shiny/driver"
 	"golang.org/x/exp/shiny/imageutil"
 	"golang.org/x/exp/shiny/screen"
@@ -32,6 +33,9 @@ type Visualizer struct {
 
 	sz          size.Event
 	crossCenter image.Point
+
+	buf screen.Buffer  // буфер для завантаження кадрів, сформованих у пам'яті
+	tex screen.Texture // текстура вікна, у яку завантажуються такі кадри
 }
 
 func (pw *Visualizer) Main() {
@@ -93,11 +97,36 @@ func (pw *Visualizer) run(s screen.Screen) {
 			pw.handleEvent(e, t)
 
 		case t = <-pw.tx:
+			t = pw.upload(s, t)
 			w.Send(paint.Event{})
 		}
 	}
 }
 
+// upload завантажує кадр, сформований у пам'яті, у текстуру вікна; інші текстури повертаються без змін.
+func (pw *Visualizer) upload(s screen.Screen, t screen.Texture) screen.Texture {
+	it, ok := t.(*painter.ImageTexture)
+	if !ok {
+		return t
+	}
+	if pw.tex == nil || pw.tex.Size() != it.Size() {
+		if pw.tex != nil {
+			pw.buf.Release()
+			pw.tex.Release()
+		}
+		var err error
+		if pw.buf, err = s.NewBuffer(it.Size()); err != nil {
+			log.Fatal("Failed to create a buffer:", err)
+		}
+		if pw.tex, err = s.NewTexture(it.Size()); err != nil {
+			log.Fatal("Failed to create a texture:", err)
+		}
+	}
+	copy(pw.buf.RGBA().Pix, it.RGBA.Pix)
+	pw.tex.Upload(image.Point{}, pw.buf, pw.buf.Bounds())
+	return pw.tex
+}
+
 func detectTerminate(e any) bool {
 	switch e := e.(type) {
 	case lifecycle.Event: